@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/lsm5/bindings-sample/pkg/demo"
+)
+
+// command is one bindings-sample subcommand.  Each parses its own
+// arguments, exiting with a usage message when they are wrong.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"run", "pull an image and walk a container through its lifecycle", func(ctx context.Context, args []string) error {
+		return demo.Run(ctx, parseRunFlags(args))
+	}},
+	{"pull", "pull an image", func(ctx context.Context, args []string) error {
+		return demo.Pull(ctx, parsePullFlags(args))
+	}},
+	{"build", "build an image from a context directory", func(ctx context.Context, args []string) error {
+		return demo.Build(ctx, parseBuildFlags(args))
+	}},
+	{"ps", "list containers", func(ctx context.Context, args []string) error {
+		return demo.List(ctx, parsePsFlags(args))
+	}},
+	{"rm", "stop and remove containers", func(ctx context.Context, args []string) error {
+		opts, names := parseRmFlags(args)
+		return demo.RemoveContainers(ctx, opts, names)
+	}},
+	{"prune", "remove stopped containers, unused images and optionally volumes", func(ctx context.Context, args []string) error {
+		return demo.Prune(ctx, parsePruneFlags(args))
+	}},
+}
+
+// findCommand returns the command called name, or nil.
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// usage prints the list of commands.
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s COMMAND [flags] [args]\n\nCommands:\n", programName)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun '%s COMMAND -h' for the flags of a command.\n", programName)
+}
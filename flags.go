@@ -0,0 +1,904 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/containers/libpod/v2/libpod/define"
+	"github.com/lsm5/bindings-sample/pkg/demo"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultImage is the image used when --image is not given.
+const defaultImage = "registry.fedoraproject.org/fedora:latest"
+
+// programName is how the usage messages refer to this program.
+const programName = "bindings-sample"
+
+// newFlagSet returns the flag set of a subcommand.  Its usage message
+// shows args, the positional arguments the command takes.
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] %s\n\nFlags:\n", programName, name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// addCommonFlags registers the flags that every command accepts.  The
+// returned function checks them once the flags have been parsed.
+func addCommonFlags(fs *flag.FlagSet, opts *demo.Options) func() {
+	fs.StringVar(&opts.Socket, "socket", "", "Podman service URI: unix:///PATH, ssh://USER@HOST[:PORT]/PATH or tcp://HOST:PORT (default: autodetect)")
+	fs.StringVar(&opts.Identity, "identity", "", "SSH identity file for an ssh:// service URI")
+	fs.StringVar(&opts.TLSCert, "tls-cert", "", "client certificate for mutual TLS with a tcp:// service URI")
+	fs.StringVar(&opts.TLSKey, "tls-key", "", "private key for --tls-cert")
+	fs.StringVar(&opts.TLSCA, "tls-ca", "", "CA certificate to verify a tcp:// service with (default: the system roots)")
+	fs.BoolVar(&opts.Insecure, "insecure", false, "allow a tcp:// service URI without TLS")
+	fs.IntVar(&opts.ConnectRetries, "connect-retries", 5, "number of attempts to connect to the service")
+	fs.DurationVar(&opts.ConnectTimeout, "connect-timeout", 30*time.Second, "give up connecting to the service after this long")
+	fs.DurationVar(&opts.SocketWait, "socket-wait", 0, "wait this long for a unix socket to appear before connecting, e.g. on a fresh boot")
+	fs.IntVar(&opts.MaxParallelOps, "max-parallel-ops", runtime.NumCPU(), "most calls to the service that concurrent steps such as --pull and --replicas make at once, in all")
+	fs.BoolVar(&jsonErrors, "json-errors", false, "on failure, print a JSON object with the failing step, the error and the exit code to stderr instead of a message")
+	logLevel := fs.String("log-level", "info", "log level: debug, info, warn or error")
+	return func() {
+		var err error
+		if opts.LogLevel, err = parseLogLevel(*logLevel); err != nil {
+			usageError(fs, err.Error())
+		}
+		if opts.ConnectRetries < 1 {
+			usageError(fs, "--connect-retries must be at least 1")
+		}
+		if opts.ConnectTimeout <= 0 {
+			usageError(fs, "--connect-timeout must be positive")
+		}
+		if opts.SocketWait < 0 {
+			usageError(fs, "--socket-wait cannot be negative")
+		}
+		if opts.MaxParallelOps < 1 {
+			usageError(fs, "--max-parallel-ops must be at least 1")
+		}
+		if (opts.TLSCert == "") != (opts.TLSKey == "") {
+			usageError(fs, "--tls-cert and --tls-key must be given together")
+		}
+		if opts.Insecure && (opts.TLSCert != "" || opts.TLSCA != "") {
+			usageError(fs, "--insecure cannot be combined with --tls-cert or --tls-ca")
+		}
+	}
+}
+
+// addPullFlags registers the flags of the commands that pull images.  The
+// returned function checks them once the flags have been parsed.
+func addPullFlags(fs *flag.FlagSet, opts *demo.Options) func() {
+	fs.StringVar(&opts.Username, "username", "", "registry username for pulling and pushing the image")
+	fs.StringVar(&opts.Password, "password", "", "registry password for pulling and pushing the image")
+	fs.StringVar(&opts.Authfile, "authfile", "", "path to a registry authentication file")
+	fs.IntVar(&opts.PullRetries, "pull-retries", 3, "retry a pull that fails with a transient error this many times")
+	fs.DurationVar(&opts.PullBackoff, "pull-backoff", time.Second, "delay before the first pull retry, doubled after each one")
+	fs.DurationVar(&opts.PullTimeout, "pull-timeout", 10*time.Minute, "give up on a pull, retries included, or a build after this long, however much --deadline is left (0 means no timeout)")
+	fs.StringVar(&opts.Arch, "arch", "", "pull the image for this architecture instead of the host's (e.g. arm64)")
+	fs.StringVar(&opts.OS, "os", "", "pull the image for this OS instead of the host's; needs --arch")
+	return func() {
+		if (opts.Username == "") != (opts.Password == "") {
+			usageError(fs, "--username and --password must be given together")
+		}
+		if opts.PullRetries < 0 {
+			usageError(fs, "--pull-retries cannot be negative")
+		}
+		if opts.PullBackoff <= 0 {
+			usageError(fs, "--pull-backoff must be positive")
+		}
+		if opts.PullTimeout < 0 {
+			usageError(fs, "--pull-timeout cannot be negative")
+		}
+		if opts.OS != "" && opts.Arch == "" {
+			usageError(fs, "--os needs --arch")
+		}
+	}
+}
+
+// parseRunFlags reads the arguments of the run command into an options
+// value.  It prints the usage message and exits non-zero when a required
+// value is missing.
+func parseRunFlags(args []string) demo.Options {
+	var opts demo.Options
+	fs := newFlagSet("run", "")
+	checkCommon := addCommonFlags(fs, &opts)
+	checkPull := addPullFlags(fs, &opts)
+	fs.StringVar(&opts.Image, "image", defaultImage, "image to pull and run, optionally pinned as NAME@sha256:DIGEST")
+	fs.BoolVar(&opts.Remove, "rm", true, "remove the container, and whatever else the tutorial created, including an image it pulled, when it finishes")
+	fs.Var((*stringSlice)(&opts.Command), "cmd", "command to run in the container, one argument per flag (repeatable)")
+	fs.Var((*stringSlice)(&opts.Env), "env", "set an environment variable in the container, as KEY=VALUE (repeatable)")
+	fs.Var((*stringSlice)(&opts.Volumes), "volume", "mount a host path or named volume, as SOURCE:DEST[:OPTS] (repeatable)")
+	fs.Var((*stringSlice)(&opts.Publish), "publish", "publish a container port, as HOSTPORT:CTRPORT[/PROTO] (repeatable)")
+	fs.StringVar(&opts.Output, "output", "text", "output format: text or json")
+	fs.BoolVar(&opts.CleanupImage, "cleanup-image", false, "remove the image when the tutorial finishes")
+	waitCondition := fs.String("wait-condition", "running", "state to wait for after start: running, stopped, exited or paused; a comma-separated list waits for whichever comes first, and the steps after it assume the first")
+	fs.DurationVar(&opts.WaitTimeout, "wait-timeout", 0, "give up waiting for the container after this long (0 waits forever)")
+	fs.Var((*stringSlice)(&opts.Exec), "exec", "command to exec in the running container, one argument per flag (repeatable); a piped stdin is passed to it")
+	fs.StringVar(&opts.Pod, "pod", "", "create a pod with this name and run the container in it")
+	fs.StringVar(&opts.InfraImage, "infra-image", "", "image for the --pod infra container (default: the service's pause image)")
+	fs.Var((*stringSlice)(&opts.InfraCommand), "infra-command", "command of the --pod infra container, one argument per flag (repeatable)")
+	fs.BoolVar(&opts.NoInfra, "no-infra", false, "create the --pod without an infra container, so its containers share no namespaces")
+	fs.StringVar(&opts.SecondImage, "second-image", "", "run a second container from this image in the --pod, and check that the first can reach its --second-port on localhost")
+	fs.Var((*stringSlice)(&opts.SecondCmd), "second-cmd", "command of the --second-image container, one argument per flag (repeatable; default: the image's)")
+	fs.IntVar(&opts.SecondPort, "second-port", 0, "port the --second-image container listens on")
+	fs.DurationVar(&opts.Stats, "stats", 0, "collect resource usage of the running container for this long")
+	fs.StringVar(&opts.Restart, "restart", "", "restart policy: no, on-failure, always or unless-stopped")
+	fs.UintVar(&opts.RestartRetries, "restart-retries", 0, "maximum restarts with --restart=on-failure")
+	fs.BoolVar(&opts.AutoRemove, "auto-remove", false, "have the service remove the container as soon as it exits; needs --wait-condition=exited and skips the steps after the wait")
+	fs.StringVar(&opts.Name, "name", "", "name of the container (default: generated)")
+	fs.BoolVar(&opts.Replace, "replace", false, "remove any existing container, replicas, --pod and --create-volume with the same names first, so reruns end in the same state")
+	fs.Var((*stringSlice)(&opts.ImageFilters), "image-filter", "filter the image list, as KEY=VALUE (repeatable)")
+	fs.BoolVar(&opts.Dangling, "dangling", false, "only list dangling images, those with no tag, as --image-filter dangling=true would")
+	fs.Var((*stringSlice)(&opts.ImageLabels), "image-label", "only list images with this label, as KEY or KEY=VALUE (repeatable)")
+	fs.IntVar(&opts.ListLimit, "list-limit", 1, "list only this many of the latest containers (0 for no limit)")
+	fs.BoolVar(&opts.All, "all", false, "list all containers, not just running ones")
+	fs.StringVar(&opts.Build, "build", "", "build the image from this context directory instead of pulling")
+	fs.StringVar(&opts.Containerfile, "file", "Containerfile", "Containerfile to build, relative to the --build directory")
+	fs.StringVar(&opts.Tag, "tag", "", "additional name for the image (default for --build: "+demo.DefaultBuildTag+")")
+	fs.BoolVar(&opts.Push, "push", false, "push the image (or its --tag) to its registry, using the pull credentials")
+	fs.BoolVar(&opts.Verbose, "verbose", false, "print more details while inspecting")
+	fs.BoolVar(&opts.Attach, "attach", false, "attach the terminal to the running container; a piped stdin is passed to it up to end-of-file")
+	fs.StringVar(&opts.DetachKeys, "detach-keys", "ctrl-p,ctrl-q", "key sequence to detach from --attach")
+	fs.StringVar(&opts.CreateVolume, "create-volume", "", "create a named volume and mount it at /mnt/NAME")
+	fs.StringVar(&opts.Network, "network", "", "attach the container to this network, creating it if needed")
+	fs.Var((*stringSlice)(&opts.NetworkAliases), "network-alias", "add a DNS alias by which other containers on --network can reach this one (repeatable)")
+	fs.DurationVar(&opts.Pause, "pause", 0, "pause the running container for this long, then unpause it")
+	fs.StringVar(&opts.Rename, "rename", "", "rename the container to this after creating it")
+	fs.StringVar(&opts.CopyIn, "copy-in", "", "copy the host file SRC into the container directory DST before starting it (SRC:DST)")
+	fs.StringVar(&opts.CopyOut, "copy-out", "", "copy the container path SRC into the host directory DST (SRC:DST)")
+	fs.StringVar(&opts.HealthCmd, "health-cmd", "", "healthcheck command, run with the container's shell")
+	fs.DurationVar(&opts.HealthInterval, "health-interval", 5*time.Second, "time between healthchecks")
+	fs.IntVar(&opts.HealthRetries, "health-retries", 3, "consecutive failed healthchecks before the container is unhealthy")
+	fs.DurationVar(&opts.HealthTimeout, "health-timeout", time.Minute, "how long to wait for the container to become healthy")
+	fs.BoolVar(&opts.LogTimestamps, "timestamps", false, "prefix each streamed log line with its time")
+	fs.StringVar(&opts.LogTail, "tail", "all", "stream only the last N log lines written before the stream starts, or all")
+	fs.StringVar(&opts.LogSince, "since", "", "stream only the logs written in the last DURATION, e.g. 5m")
+	fs.BoolVar(&opts.WatchEvents, "watch-events", false, "print the container's events as they happen")
+	fs.BoolVar(&opts.WatchState, "watch-state", false, "print the container's state, with a timestamp, each time it changes until it exits")
+	fs.DurationVar(&opts.WatchStateInterval, "watch-state-interval", 100*time.Millisecond, "how often --watch-state polls the container's state")
+	fs.IntVar(&opts.StreamReconnects, "stream-reconnects", 3, "reconnect a log, stats or events stream this many times if its connection drops (0 to give up at once)")
+	fs.StringVar(&opts.User, "user", "", "run the container's process as this user (uid, uid:gid or name)")
+	fs.Var((*stringSlice)(&opts.GroupAdd), "group-add", "add the container's user to this supplementary group, by gid or name (repeatable)")
+	fs.StringVar(&opts.Workdir, "workdir", "", "working directory of the container's process")
+	fs.StringVar(&opts.Umask, "umask", "", "octal umask of the container's process, e.g. 0077; needs --entrypoint or a command, and a shell in the image")
+	fs.StringVar(&opts.TZ, "tz", "", "timezone of the container, a zone name such as Europe/Prague; with --exec, date is run to show it")
+	fs.StringVar(&opts.Memory, "memory", "", "memory limit, in bytes or with a k, m or g suffix (e.g. 128m)")
+	fs.StringVar(&opts.ShmSize, "shm-size", "", "size of /dev/shm, in bytes or with a k, m or g suffix (e.g. 64m) (default: the service's, usually 64m)")
+	fs.IntVar(&opts.OOMScoreAdj, "oom-score-adj", 0, "adjust the container's OOM score, from -1000 (never kill) to 1000 (kill first)")
+	fs.Float64Var(&opts.CPUs, "cpus", 0, "number of CPUs the container may use (e.g. 0.5)")
+	fs.StringVar(&opts.Export, "export", "", "export the container's filesystem to this tar file")
+	fs.StringVar(&opts.Save, "save", "", "save the image to this archive file")
+	fs.StringVar(&opts.Load, "load", "", "load images from this archive file before pulling")
+	fs.BoolVar(&opts.Quiet, "quiet", false, "only print the container ID, or the summary with --output=json")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "print the container spec as JSON and exit without contacting the service")
+	fs.StringVar(&opts.Manifest, "manifest", "", "create a manifest list with this name holding the image; pushed with --push")
+	fs.StringVar(&opts.Commit, "commit", "", "commit the container to a new image with this name")
+	fs.StringVar(&opts.CommitAuthor, "commit-author", "", "author of the committed image")
+	fs.StringVar(&opts.CommitMessage, "commit-message", "", "commit message of the committed image")
+	fs.Var((*stringSlice)(&opts.CommitChanges), "commit-change", "Containerfile instruction to apply to the committed image, e.g. 'CMD [\"/bin/sh\"]' (repeatable)")
+	fs.StringVar(&opts.ContainerToImage, "container-to-image", "", "export the container's filesystem and import it as a new, single-layer image with this name")
+	fs.Var((*stringSlice)(&opts.Changes), "change", "Containerfile instruction to apply to the --container-to-image image, e.g. 'CMD [\"/bin/sh\"]' (repeatable)")
+	fs.Var((*stringSlice)(&opts.Requires), "requires", "start this container, by name or ID, before the tutorial's whenever it starts (repeatable)")
+	fs.BoolVar(&opts.CreateDependency, "create-dependency", false, "create a sleeping container from the image and --requires it, to show it being started first")
+	fs.Var((*stringSlice)(&opts.Labels), "label", "set a container label, KEY=VALUE (repeatable)")
+	fs.Var((*stringSlice)(&opts.Annotations), "annotation", "set an OCI annotation, KEY=VALUE, which the runtime sees rather than podman (repeatable)")
+	fs.Var((*stringSlice)(&opts.ContainerFilters), "filter", "filter the container list, KEY=VALUE such as label=app=demo (repeatable)")
+	fs.Var((*stringSlice)(&opts.Tmpfs), "tmpfs", "mount a tmpfs at PATH[:OPTS], e.g. /scratch:size=16m (repeatable)")
+	fs.Var((*stringSlice)(&opts.MountTypes), "mount-type", "add a mount in podman run --mount syntax: type=bind|volume|tmpfs|image,target=PATH[,source=...][,readonly]... (repeatable)")
+	fs.BoolVar(&opts.Diff, "diff", false, "show the changes the container made to its filesystem")
+	fs.BoolVar(&opts.Top, "top", false, "list the processes of the running container")
+	fs.StringVar(&opts.TopDescriptors, "top-descriptors", "pid,user,args", "comma-separated ps descriptors for --top")
+	fs.BoolVar(&opts.Kill, "kill", false, "kill the container with --kill-signal instead of stopping it")
+	killSignal := fs.String("kill-signal", "SIGKILL", "signal sent by --kill, by name (SIGKILL, KILL) or number (9)")
+	stopSignal := fs.String("stop-signal", "", "signal that stops the container gracefully, by name (SIGUSR1, USR1) or number (10) (default: the image's, or SIGTERM)")
+	fs.IntVar(&opts.StopTimeout, "stop-timeout", 10, "seconds to wait for the container to stop before killing it; 0 kills it immediately")
+	fs.BoolVar(&opts.Init, "init", false, "run an init process as PID 1 that reaps zombie processes")
+	fs.StringVar(&opts.InitPath, "init-path", "", "path to the init binary on the host (default: the service's catatonit)")
+	fs.Var((*stringSlice)(&opts.CapAdd), "cap-add", "add a Linux capability, e.g. NET_ADMIN or ALL (repeatable)")
+	fs.Var((*stringSlice)(&opts.CapDrop), "cap-drop", "drop a Linux capability, e.g. CAP_CHOWN or ALL (repeatable)")
+	fs.BoolVar(&opts.Privileged, "privileged", false, "give the container extended privileges")
+	fs.StringVar(&opts.SeccompProfile, "seccomp-profile", "", "confine the container with this JSON seccomp profile, at the same path on the service's host, or unconfined")
+	fs.StringVar(&opts.Apparmor, "apparmor", "", "confine the container with this AppArmor profile, or unconfined")
+	fs.BoolVar(&opts.ReadOnly, "read-only", false, "mount the container's root filesystem read-only")
+	fs.BoolVar(&opts.ReadOnlyTmpfs, "read-only-tmpfs", true, "with --read-only, mount writable tmpfs filesystems on /run, /tmp and /var/tmp")
+	fs.Var((*stringSlice)(&opts.Sysctls), "sysctl", "set a namespaced kernel parameter, KEY=VALUE (repeatable)")
+	fs.Var((*stringSlice)(&opts.Ulimits), "ulimit", "set a resource limit, NAME=SOFT:HARD such as nofile=1024:4096 (repeatable)")
+	fs.Var((*stringSlice)(&opts.Devices), "device", "pass a host device into the container, HOST[:CONTAINER[:PERMS]] with PERMS of r, w and m, e.g. /dev/ttyUSB0:/dev/serial:rw (repeatable)")
+	fs.StringVar(&opts.Hostname, "hostname", "", "hostname of the container")
+	fs.Var((*stringSlice)(&opts.DNS), "dns", "DNS server for the container to use (repeatable)")
+	fs.Var((*stringSlice)(&opts.DNSSearch), "dns-search", "DNS search domain for the container (repeatable)")
+	fs.Var((*stringSlice)(&opts.AddHosts), "add-host", "add a HOST:IP entry to the container's /etc/hosts (repeatable)")
+	fs.StringVar(&opts.Entrypoint, "entrypoint", "", "override the image's entrypoint, as a single command or a JSON array such as '[\"/bin/sh\", \"-c\"]'")
+	fs.BoolVar(&opts.NoEntrypoint, "no-entrypoint", false, "clear the image's entrypoint, so that the command runs on its own; the same as --entrypoint \"\"")
+	fs.BoolVar(&opts.ShellParse, "shell-parse", false, "split --entrypoint and each --cmd into arguments like a shell, e.g. --cmd \"sh -c 'echo hi'\"")
+	fs.Var((*stringSlice)(&opts.Secrets), "secret", "mount this podman secret in the container as the file /run/secrets/NAME (repeatable)")
+	fs.Var((*stringSlice)(&opts.CreateSecrets), "create-secret", "create a podman secret from a file, NAME=FILE, for --secret or --secret-env to use; --rm removes it again (repeatable)")
+	fs.BoolVar(&opts.GenerateSystemd, "generate-systemd", false, "generate systemd units for the container")
+	fs.StringVar(&opts.UnitDir, "unit-dir", ".", "directory to write the --generate-systemd units to")
+	fs.StringVar(&opts.GenerateKube, "generate-kube", "", "write Kubernetes YAML for the container, or its pod with --pod, to this file")
+	fs.StringVar(&opts.PlayKube, "play-kube", "", "deploy the pods in this Kubernetes YAML file instead of running a single container")
+	fs.BoolVar(&opts.Down, "down", false, "with --play-kube, remove the pods the file deployed")
+	fs.StringVar(&opts.Checkpoint, "checkpoint", "", "checkpoint the running container to this archive")
+	fs.BoolVar(&opts.LeaveRunning, "checkpoint-leave-running", false, "leave the container running after --checkpoint, as the source of a migration would be")
+	fs.StringVar(&opts.Restore, "restore", "", "with --checkpoint, restore the container from this archive")
+	fs.StringVar(&opts.RestoreName, "restore-name", "", "restore a new container with this name from the --restore archive, as at the end of a migration, rather than the checkpointed one")
+	fs.IntVar(&opts.Replicas, "replicas", 1, "run this many copies of the container; the steps after start only act on the first")
+	fs.DurationVar(&opts.Deadline, "deadline", 0, "abort the whole run, cleaning up, if it takes longer than this (0 means no deadline)")
+	fs.StringVar(&opts.ManagePod, "manage-pod", "", "run --pod-actions on this existing pod instead of running a container")
+	fs.StringVar(&opts.PodActions, "pod-actions", "start,stats,pause,unpause,stop", "comma-separated pod operations for --manage-pod: start, stop, pause, unpause and stats")
+	fs.StringVar(&opts.UpdateMemory, "update-memory", "", "change the memory limit of the running container, like --memory")
+	fs.Float64Var(&opts.UpdateCPUs, "update-cpus", 0, "change the CPU limit of the running container, like --cpus")
+	fs.BoolVar(&opts.Mount, "mount", false, "mount the container's root filesystem and print its path on the host")
+	fs.StringVar(&opts.Format, "format", "", "print the container list with this Go template, e.g. '{{.ID}} {{.Names}}'")
+	fs.StringVar(&opts.ImageFormat, "image-format", "", "print the image list with this Go template, e.g. '{{.Repository}}:{{.Tag}} {{.Size}}'")
+	fs.StringVar(&opts.PullPolicy, "pull-policy", "missing", "when to pull the image: always, missing (only if not present) or never")
+	fs.BoolVar(&opts.History, "history", false, "list the layers of the image and the commands that created them")
+	fs.IntVar(&opts.HistoryWidth, "history-width", 45, "cut --history commands longer than this many characters (0 means never)")
+	fs.BoolVar(&opts.EnvHost, "env-host", false, "copy this program's environment into the container; --env-file and --env take precedence. Beware: secrets in it are exposed too")
+	fs.Var((*stringSlice)(&opts.EnvFiles), "env-file", "read environment variables from this file of KEY=VALUE lines; --env takes precedence (repeatable)")
+	fs.Var((*stringSlice)(&opts.SecretEnv), "secret-env", "expose this podman secret as an environment variable, SECRET=ENVVAR, rather than as a file like --secret; with --exec, check it is set (repeatable)")
+	fs.Var((*stringSlice)(&opts.LabelFiles), "label-file", "read container labels from this file of KEY=VALUE lines; --label takes precedence (repeatable)")
+	fs.StringVar(&opts.EventsLog, "events-log", "", "write each step, with its timing, status and the IDs it produced, to this file as JSON lines")
+	fs.BoolVar(&opts.KeepImage, "keep-image", false, "keep the image the tutorial pulled, even with --rm")
+	fs.BoolVar(&opts.NoPull, "no-pull", false, "never contact a registry: fail if the image, or a --build base image, is not present locally; implies --pull-policy=never")
+	fs.BoolVar(&opts.RestartContainer, "restart-container", false, "restart the running container with a single call and wait for it to be running again")
+	fs.IntVar(&opts.RestartTimeout, "restart-timeout", 10, "seconds --restart-container gives the container to stop before killing it")
+	fs.StringVar(&opts.CgroupNS, "cgroupns", "", "cgroup namespace: host, private, ns:PATH or container:ID")
+	fs.StringVar(&opts.PidNS, "pidns", "", "PID namespace: host, private, ns:PATH or container:ID")
+	fs.StringVar(&opts.IpcNS, "ipcns", "", "IPC namespace: host, private, ns:PATH or container:ID")
+	fs.StringVar(&opts.UtsNS, "utsns", "", "UTS namespace: host, private, ns:PATH or container:ID")
+	fs.StringVar(&opts.UserNS, "userns", "", "user namespace: host, keep-id, auto or nomap")
+	fs.Var((*stringSlice)(&opts.UIDMaps), "uidmap", "map container UIDs to host UIDs in a private user namespace, as CONTAINER_ID:HOST_ID:SIZE (repeatable)")
+	fs.Var((*stringSlice)(&opts.GIDMaps), "gidmap", "map container GIDs to host GIDs, as CONTAINER_ID:HOST_ID:SIZE (repeatable; default: the --uidmap mappings)")
+	fs.StringVar(&opts.WaitForPort, "wait-for-port", "", "once the container is running, wait for this HOST:PORT, the host side of a --publish mapping, to accept connections")
+	fs.DurationVar(&opts.WaitForPortTimeout, "wait-for-port-timeout", time.Minute, "how long to wait for --wait-for-port")
+	fs.Var((*stringSlice)(&opts.StartupCmd), "startup-cmd", "once the container is running, exec this command in it until it exits 0, one argument per flag (repeatable)")
+	fs.IntVar(&opts.StartupRetries, "startup-retries", 10, "how many times to run --startup-cmd before giving up")
+	fs.DurationVar(&opts.StartupInterval, "startup-interval", time.Second, "time between --startup-cmd attempts")
+	fs.BoolVar(&opts.ListNetworks, "list-networks", false, "list the networks, like podman network ls, after listing containers")
+	fs.BoolVar(&opts.ListVolumes, "list-volumes", false, "list the volumes, like podman volume ls, after listing containers")
+	fs.BoolVar(&opts.DiskUsage, "disk-usage", false, "show the space taken by images, containers and volumes, like podman system df; included in --output json")
+	fs.BoolVar(&opts.NoExitCode, "no-exit-code", false, "exit 0 when the container exits non-zero, instead of with its exit code")
+	fs.Var((*stringSlice)(&opts.PullImages), "pull", "pull this image, concurrently with the other --pull images, and run the first one pulled instead of --image (repeatable)")
+	fs.IntVar(&opts.PullParallelism, "pull-parallelism", 3, "pull at most this many --pull images at a time")
+	fs.BoolVar(&opts.AllTags, "all-tags", false, "pull every tag of the --image repository, given without a tag, and run the --all-tags-run one")
+	fs.StringVar(&opts.AllTagsRun, "all-tags-run", "latest", "the tag to run after --all-tags")
+	fs.BoolVar(&opts.FailFast, "fail-fast", false, "stop the other --pull pulls, and the run, as soon as one fails")
+	fs.BoolVar(&opts.Supervise, "supervise", false, "once the container exits non-zero, remove it and run a new one, up to --max-restarts times")
+	fs.IntVar(&opts.MaxRestarts, "max-restarts", 3, "how many times --supervise runs a new container")
+	fs.DurationVar(&opts.SuperviseBackoff, "supervise-backoff", time.Second, "how long --supervise waits before the first new container, doubling each time")
+	fs.StringVar(&opts.SpecFile, "spec", "", "start from the SpecGenerator in this JSON file; flags that are set, on the command line or in --config, override its fields, and maps such as env and labels are merged key by key")
+	fs.BoolVar(&opts.Detach, "detach", false, "print the container's ID once it has started and exit, leaving it running and skipping the wait, logs, stop and cleanup")
+	fs.StringVar(&opts.SdNotify, "sdnotify", "conmon", "when run as a systemd Type=notify unit: conmon sends READY=1 once the container is running, container leaves that to the container, ignore sends nothing")
+	fs.StringVar(&opts.Rootfs, "rootfs", "", "run the container from this root filesystem directory, on the service's host, instead of an image; nothing is pulled")
+	fs.StringVar(&opts.AttachExisting, "attach-existing", "", "instead of creating a container, follow the logs of (or --attach to) this existing one and run --exec, --top and --stats against it; it is never removed")
+	fs.StringVar(&opts.CIDFile, "cidfile", "", "write the container's ID to this file once it is created; removed with --rm")
+	fs.StringVar(&opts.InspectOut, "inspect-out", "", "write the container's full inspect data to this file as JSON, creating its directory if needed")
+	fs.BoolVar(&opts.Force, "force", false, "overwrite an existing --cidfile or --inspect-out file")
+	fs.StringVar(&opts.Search, "search", "", "search the registries for images matching this term before pulling")
+	fs.IntVar(&opts.SearchLimit, "search-limit", 25, "show at most this many --search results per registry")
+	fs.Var((*stringSlice)(&opts.SearchFilters), "search-filter", "filter the --search results: stars=N, is-official=BOOL or is-automated=BOOL (repeatable)")
+	fs.BoolVar(&opts.Timings, "timings", false, "report how long connecting, pulling, creating, starting, waiting and stopping took, and the whole run; included in --output json")
+	fs.BoolVar(&opts.ValidateOnly, "validate-only", false, "check the flags, the container spec and the connection to the service, report every problem found, and exit without pulling or creating anything")
+	fs.BoolVar(&opts.StrictFeatures, "strict-features", false, "fail when the service is too old for an option, such as --update-memory, instead of skipping that option with a warning")
+	config := fs.String("config", "", "read flag values from this YAML or JSON file; flags on the command line take precedence")
+	fs.Parse(args)
+	if *config != "" {
+		if err := loadConfig(fs, *config); err != nil {
+			usageError(fs, err.Error())
+		}
+	}
+	if opts.ValidateOnly {
+		collectedUsageErrors = &opts.UsageErrors
+	}
+	if fs.NArg() > 0 {
+		usageError(fs, fmt.Sprintf("run takes no arguments, got %q", fs.Arg(0)))
+	}
+
+	checkCommon()
+	checkPull()
+
+	var err error
+	if opts.WaitConditions, err = parseWaitConditions(*waitCondition); err != nil {
+		usageError(fs, err.Error())
+	}
+	// Under --validate-only a bad list is only recorded, leaving none
+	if len(opts.WaitConditions) == 0 {
+		opts.WaitConditions = []define.ContainerStatus{define.ContainerStateUnknown}
+	}
+	opts.WaitCondition = opts.WaitConditions[0]
+	if opts.KillSignal, err = parseSignal("--kill-signal", *killSignal); err != nil {
+		usageError(fs, err.Error())
+	}
+	if *stopSignal != "" {
+		if opts.StopSignal, err = parseSignal("--stop-signal", *stopSignal); err != nil {
+			usageError(fs, err.Error())
+		}
+	}
+
+	// A --rootfs container has no image, and the image in a --spec file
+	// is only overridden by an explicit --image
+	if opts.Rootfs != "" {
+		if isSet(fs, "image") || opts.Build != "" || len(opts.PullImages) > 0 || opts.Tag != "" || opts.Push ||
+			opts.Save != "" || opts.History || opts.Manifest != "" || opts.CleanupImage || opts.Arch != "" {
+			usageError(fs, "--rootfs runs no image and cannot be combined with --image, --build, --pull, --tag, --push, --save, --history, --manifest, --cleanup-image or --arch")
+		}
+		opts.Image = ""
+	} else if opts.SpecFile != "" {
+		if !isSet(fs, "image") {
+			opts.Image = ""
+		}
+	} else if opts.Image == "" {
+		usageError(fs, "--image must not be empty")
+	}
+	if opts.Output != "text" && opts.Output != "json" {
+		usageError(fs, fmt.Sprintf("--output must be text or json, not %q", opts.Output))
+	}
+	if len(opts.Exec) > 0 && opts.WaitCondition != define.ContainerStateRunning {
+		usageError(fs, "--exec needs --wait-condition=running")
+	}
+	if opts.Attach && opts.WaitCondition != define.ContainerStateRunning {
+		usageError(fs, "--attach needs --wait-condition=running")
+	}
+	if opts.Stats > 0 && opts.WaitCondition != define.ContainerStateRunning {
+		usageError(fs, "--stats needs --wait-condition=running")
+	}
+	if opts.RestartContainer && opts.WaitCondition != define.ContainerStateRunning {
+		usageError(fs, "--restart-container needs --wait-condition=running")
+	}
+	if opts.RestartTimeout < 0 {
+		usageError(fs, "--restart-timeout cannot be negative")
+	}
+	if opts.Pause > 0 && opts.WaitCondition != define.ContainerStateRunning {
+		usageError(fs, "--pause needs --wait-condition=running")
+	}
+	if opts.HealthCmd != "" {
+		if opts.WaitCondition != define.ContainerStateRunning {
+			usageError(fs, "--health-cmd needs --wait-condition=running")
+		}
+		if opts.HealthInterval <= 0 || opts.HealthTimeout <= 0 {
+			usageError(fs, "--health-interval and --health-timeout must be positive")
+		}
+		if opts.HealthRetries < 1 {
+			usageError(fs, "--health-retries must be at least 1")
+		}
+	}
+	if opts.WaitForPort != "" {
+		if opts.WaitCondition != define.ContainerStateRunning {
+			usageError(fs, "--wait-for-port needs --wait-condition=running")
+		}
+		if opts.WaitForPortTimeout <= 0 {
+			usageError(fs, "--wait-for-port-timeout must be positive")
+		}
+	}
+	if len(opts.StartupCmd) > 0 {
+		if opts.WaitCondition != define.ContainerStateRunning {
+			usageError(fs, "--startup-cmd needs --wait-condition=running")
+		}
+		if opts.StartupRetries < 1 {
+			usageError(fs, "--startup-retries must be at least 1")
+		}
+		if opts.StartupInterval <= 0 {
+			usageError(fs, "--startup-interval must be positive")
+		}
+	}
+	if opts.Build != "" && opts.Tag == "" {
+		opts.Tag = demo.DefaultBuildTag
+	}
+	if opts.ListLimit < 0 {
+		usageError(fs, "--list-limit must not be negative")
+	}
+	if (opts.Arch != "" || opts.OS != "") && opts.Build != "" {
+		usageError(fs, "--arch and --os only apply to pulled images, not --build")
+	}
+	if opts.Commit == "" && (opts.CommitAuthor != "" || opts.CommitMessage != "" || len(opts.CommitChanges) > 0) {
+		usageError(fs, "--commit-author, --commit-message and --commit-change need --commit")
+	}
+	if opts.ContainerToImage == "" && len(opts.Changes) > 0 {
+		usageError(fs, "--change needs --container-to-image")
+	}
+	if opts.Top && strings.Trim(opts.TopDescriptors, ", ") == "" {
+		usageError(fs, "--top-descriptors must not be empty")
+	}
+	if opts.SecondImage != "" {
+		if opts.Pod == "" || opts.NoInfra {
+			usageError(fs, "--second-image shares the network of the --pod, so it needs --pod and cannot be combined with --no-infra")
+		}
+		if opts.SecondPort < 1 || opts.SecondPort > 65535 {
+			usageError(fs, "--second-image needs --second-port, the port its container listens on, from 1 to 65535")
+		}
+		if opts.WaitCondition != define.ContainerStateRunning || opts.Detach {
+			usageError(fs, "--second-image checks the pod's network from the running container, so it needs --wait-condition=running and cannot be combined with --detach")
+		}
+	} else if len(opts.SecondCmd) > 0 || opts.SecondPort != 0 {
+		usageError(fs, "--second-cmd and --second-port need --second-image")
+	}
+	if opts.Pod == "" && (opts.InfraImage != "" || len(opts.InfraCommand) > 0 || opts.NoInfra) {
+		usageError(fs, "--infra-image, --infra-command and --no-infra need --pod")
+	}
+	if opts.NoInfra && (opts.InfraImage != "" || len(opts.InfraCommand) > 0) {
+		usageError(fs, "--no-infra cannot be combined with --infra-image or --infra-command")
+	}
+	if opts.Hostname != "" && opts.Pod != "" && !opts.NoInfra {
+		usageError(fs, "--hostname cannot be combined with --pod, whose infra container owns the UTS namespace, unless --no-infra is given")
+	}
+	if opts.InitPath != "" && !opts.Init {
+		usageError(fs, "--init-path needs --init")
+	}
+	if opts.Down && opts.PlayKube == "" {
+		usageError(fs, "--down needs --play-kube")
+	}
+	if opts.PlayKube != "" && (opts.Build != "" || opts.Pod != "" || opts.GenerateKube != "") {
+		usageError(fs, "--play-kube cannot be combined with --build, --pod or --generate-kube")
+	}
+	if opts.Checkpoint != "" && opts.WaitCondition != define.ContainerStateRunning {
+		usageError(fs, "--checkpoint needs --wait-condition=running")
+	}
+	if opts.Restore != "" && opts.Checkpoint == "" {
+		usageError(fs, "--restore needs --checkpoint")
+	}
+	if opts.LeaveRunning && opts.Checkpoint == "" {
+		usageError(fs, "--checkpoint-leave-running needs --checkpoint")
+	}
+	if opts.RestoreName != "" {
+		switch {
+		case opts.Restore == "":
+			usageError(fs, "--restore-name needs --restore")
+		case opts.RestoreName == opts.Name || opts.RestoreName == opts.Rename:
+			usageError(fs, "--restore-name must differ from the container's name, which the checkpointed container keeps")
+		}
+	} else if opts.LeaveRunning && opts.Restore != "" {
+		usageError(fs, "--checkpoint-leave-running with --restore needs --restore-name; the running container cannot be restored into")
+	}
+	if opts.Replicas < 1 {
+		usageError(fs, "--replicas must be at least 1")
+	}
+	if opts.Replicas > 1 && (len(opts.Requires) > 0 || opts.CreateDependency) {
+		usageError(fs, "--requires and --create-dependency cannot be combined with --replicas")
+	}
+	if opts.CreateDependency && opts.Rootfs != "" {
+		usageError(fs, "--create-dependency needs an image, which --rootfs rules out")
+	}
+	if opts.Replicas > 1 && len(opts.Publish) > 0 {
+		usageError(fs, "--replicas cannot be combined with --publish, the replicas would need the same host ports")
+	}
+	if opts.Deadline < 0 {
+		usageError(fs, "--deadline cannot be negative")
+	}
+	if opts.ManagePod != "" && opts.PlayKube != "" {
+		usageError(fs, "--manage-pod cannot be combined with --play-kube")
+	}
+	if (opts.UpdateMemory != "" || opts.UpdateCPUs != 0) && opts.WaitCondition != define.ContainerStateRunning {
+		usageError(fs, "--update-memory and --update-cpus need --wait-condition=running")
+	}
+	if opts.UpdateCPUs < 0 {
+		usageError(fs, "--update-cpus cannot be negative")
+	}
+	if len(opts.PullImages) > 0 {
+		if opts.Build != "" {
+			usageError(fs, "--pull and --build cannot be combined")
+		}
+		if opts.PullParallelism < 1 {
+			usageError(fs, "--pull-parallelism must be at least 1")
+		}
+	} else if opts.FailFast {
+		usageError(fs, "--fail-fast only applies to --pull")
+	}
+	if opts.AllTags {
+		switch {
+		case len(opts.PullImages) > 0:
+			usageError(fs, "--all-tags and --pull cannot be combined")
+		case opts.Build != "" || opts.Rootfs != "":
+			usageError(fs, "--all-tags cannot be combined with --build or --rootfs, which pull nothing")
+		case opts.NoPull:
+			usageError(fs, "--all-tags needs a registry, which --no-pull rules out")
+		case isSet(fs, "pull-policy"):
+			usageError(fs, "--all-tags always pulls, so it cannot be combined with --pull-policy")
+		}
+	} else if isSet(fs, "all-tags-run") {
+		usageError(fs, "--all-tags-run needs --all-tags")
+	}
+	// --no-pull disables all registry access
+	if opts.NoPull {
+		if len(opts.PullImages) > 0 {
+			usageError(fs, "--pull needs a registry, which --no-pull rules out")
+		}
+		if opts.PullPolicy == "always" {
+			usageError(fs, "--no-pull cannot be combined with --pull-policy=always")
+		}
+		if opts.Push {
+			usageError(fs, "--push needs a registry, which --no-pull rules out")
+		}
+		opts.PullPolicy = "never"
+	}
+	switch opts.PullPolicy {
+	case "always", "missing", "never":
+	default:
+		usageError(fs, fmt.Sprintf("--pull-policy must be always, missing or never, not %q", opts.PullPolicy))
+	}
+	if opts.HistoryWidth < 0 {
+		usageError(fs, "--history-width cannot be negative")
+	}
+	if opts.KeepImage && opts.CleanupImage {
+		usageError(fs, "--keep-image and --cleanup-image cannot be combined")
+	}
+	if opts.UserNS != "" && (len(opts.UIDMaps) > 0 || len(opts.GIDMaps) > 0) {
+		usageError(fs, "--uidmap and --gidmap set up their own user namespace and cannot be combined with --userns")
+	}
+	if opts.Attach && (opts.LogTimestamps || opts.LogTail != "all" || opts.LogSince != "") {
+		usageError(fs, "--timestamps, --tail and --since apply to the streamed logs, which --attach replaces")
+	}
+	if opts.LogTail != "all" {
+		if n, err := strconv.Atoi(opts.LogTail); err != nil || n < 0 {
+			usageError(fs, fmt.Sprintf("--tail must be a non-negative number of lines or all, not %q", opts.LogTail))
+		}
+	}
+	if opts.LogSince != "" {
+		if d, err := time.ParseDuration(opts.LogSince); err != nil || d < 0 {
+			usageError(fs, fmt.Sprintf("--since must be a positive duration such as 5m, not %q", opts.LogSince))
+		}
+	}
+	if opts.Supervise {
+		if len(opts.WaitConditions) != 1 || opts.WaitCondition != define.ContainerStateExited {
+			usageError(fs, "--supervise waits for the container to exit and requires --wait-condition=exited")
+		}
+		if opts.Attach || opts.Replicas > 1 || opts.Mount || opts.WatchEvents || opts.WatchState {
+			usageError(fs, "--supervise only follows the logs of one container and cannot be combined with --attach, --replicas, --mount, --watch-events or --watch-state")
+		}
+		if opts.Restart != "" && opts.Restart != "no" {
+			usageError(fs, "--supervise replaces --restart; the service would restart the container itself")
+		}
+		if opts.MaxRestarts < 0 {
+			usageError(fs, "--max-restarts cannot be negative")
+		}
+		if opts.SuperviseBackoff <= 0 {
+			usageError(fs, "--supervise-backoff must be positive")
+		}
+	}
+	if opts.AutoRemove {
+		if len(opts.WaitConditions) > 1 || opts.WaitCondition != define.ContainerStateExited {
+			usageError(fs, "--auto-remove removes the container once it exits and requires --wait-condition=exited")
+		}
+		if opts.Supervise || opts.Replicas > 1 || opts.Top || opts.CopyOut != "" || opts.Export != "" || opts.Diff || opts.Commit != "" || opts.ContainerToImage != "" || opts.InspectOut != "" {
+			usageError(fs, "--auto-remove leaves no container to look at after it exits and cannot be combined with --supervise, --replicas, --top, --copy-out, --export, --diff, --commit, --container-to-image or --inspect-out")
+		}
+	}
+	switch opts.SdNotify {
+	case "conmon", "container", "ignore":
+	default:
+		usageError(fs, fmt.Sprintf("--sdnotify must be conmon, container or ignore, not %q", opts.SdNotify))
+	}
+	if opts.Detach {
+		if isSet(fs, "rm") && opts.Remove {
+			usageError(fs, "--rm and --detach cannot be combined; a detached container is left running")
+		}
+		if opts.Attach || opts.Supervise || opts.CleanupImage || opts.InspectOut != "" {
+			usageError(fs, "--detach leaves the container running and cannot be combined with --attach, --supervise, --cleanup-image or --inspect-out")
+		}
+		opts.Remove = false
+	}
+	if opts.OOMScoreAdj < -1000 || opts.OOMScoreAdj > 1000 {
+		usageError(fs, fmt.Sprintf("--oom-score-adj must be between -1000 and 1000, not %d", opts.OOMScoreAdj))
+	}
+	if opts.AttachExisting != "" {
+		if isSet(fs, "image") || opts.Build != "" || len(opts.PullImages) > 0 || opts.Rootfs != "" || opts.SpecFile != "" ||
+			opts.Name != "" || opts.Replace || opts.Replicas > 1 || opts.Supervise || opts.Detach {
+			usageError(fs, "--attach-existing creates nothing and cannot be combined with --image, --build, --pull, --rootfs, --spec, --name, --replace, --replicas, --supervise or --detach")
+		}
+	}
+	if isSet(fs, "entrypoint") && opts.Entrypoint == "" {
+		opts.NoEntrypoint = true
+	} else if opts.NoEntrypoint && opts.Entrypoint != "" {
+		usageError(fs, "--no-entrypoint and --entrypoint cannot be combined")
+	}
+	if opts.WatchStateInterval <= 0 {
+		usageError(fs, "--watch-state-interval must be positive")
+	}
+	if opts.ValidateOnly && opts.DryRun {
+		usageError(fs, "--validate-only and --dry-run cannot be combined")
+	}
+	if opts.StreamReconnects < 0 {
+		usageError(fs, "--stream-reconnects cannot be negative")
+	}
+	if opts.SearchLimit <= 0 {
+		usageError(fs, "--search-limit must be positive")
+	}
+	if len(opts.SearchFilters) > 0 && opts.Search == "" {
+		usageError(fs, "--search-filter needs --search")
+	}
+	if opts.Force && opts.CIDFile == "" && opts.InspectOut == "" {
+		usageError(fs, "--force only applies to --cidfile and --inspect-out")
+	}
+	if opts.StopTimeout < 0 {
+		usageError(fs, "--stop-timeout cannot be negative")
+	}
+	if opts.CPUs < 0 {
+		usageError(fs, "--cpus cannot be negative")
+	}
+	if opts.Replace && opts.Name == "" && opts.Pod == "" && opts.CreateVolume == "" {
+		usageError(fs, "--replace needs --name, --pod or --create-volume")
+	}
+	if opts.Network != "" && opts.Pod != "" && !opts.NoInfra {
+		usageError(fs, "--network cannot be combined with --pod, whose infra container owns the network namespace, unless --no-infra is given")
+	}
+	collectedUsageErrors = nil
+	return opts
+}
+
+// parsePullFlags reads the arguments of the pull command.
+func parsePullFlags(args []string) demo.Options {
+	var opts demo.Options
+	fs := newFlagSet("pull", "IMAGE")
+	checkCommon := addCommonFlags(fs, &opts)
+	checkPull := addPullFlags(fs, &opts)
+	fs.Parse(args)
+	checkCommon()
+	checkPull()
+	if fs.NArg() != 1 {
+		usageError(fs, "pull needs exactly one IMAGE")
+	}
+	opts.Image = fs.Arg(0)
+	return opts
+}
+
+// parseBuildFlags reads the arguments of the build command.
+func parseBuildFlags(args []string) demo.Options {
+	var opts demo.Options
+	fs := newFlagSet("build", "CONTEXT")
+	checkCommon := addCommonFlags(fs, &opts)
+	fs.StringVar(&opts.Containerfile, "file", "Containerfile", "Containerfile to build, relative to CONTEXT")
+	fs.StringVar(&opts.Tag, "tag", demo.DefaultBuildTag, "name of the built image")
+	fs.DurationVar(&opts.PullTimeout, "pull-timeout", 10*time.Minute, "give up on the build after this long (0 means no timeout)")
+	fs.Parse(args)
+	checkCommon()
+	if fs.NArg() != 1 {
+		usageError(fs, "build needs exactly one CONTEXT directory")
+	}
+	if opts.Tag == "" {
+		usageError(fs, "--tag must not be empty")
+	}
+	if opts.PullTimeout < 0 {
+		usageError(fs, "--pull-timeout cannot be negative")
+	}
+	opts.Build = fs.Arg(0)
+	return opts
+}
+
+// parsePsFlags reads the arguments of the ps command.
+func parsePsFlags(args []string) demo.Options {
+	var opts demo.Options
+	fs := newFlagSet("ps", "")
+	checkCommon := addCommonFlags(fs, &opts)
+	fs.BoolVar(&opts.All, "all", false, "list all containers, not just running ones")
+	fs.Var((*stringSlice)(&opts.ContainerFilters), "filter", "filter the container list, KEY=VALUE such as label=app=demo (repeatable)")
+	fs.IntVar(&opts.ListLimit, "last", 0, "list only this many of the latest containers (0 for no limit)")
+	fs.StringVar(&opts.Format, "format", "", "print each container with this Go template, e.g. '{{.ID}} {{.Names}}'")
+	fs.Parse(args)
+	checkCommon()
+	if fs.NArg() > 0 {
+		usageError(fs, fmt.Sprintf("ps takes no arguments, got %q", fs.Arg(0)))
+	}
+	if opts.ListLimit < 0 {
+		usageError(fs, "--last must not be negative")
+	}
+	return opts
+}
+
+// parseRmFlags reads the arguments of the rm command and returns the
+// containers to remove.
+func parseRmFlags(args []string) (demo.Options, []string) {
+	var opts demo.Options
+	fs := newFlagSet("rm", "CONTAINER...")
+	checkCommon := addCommonFlags(fs, &opts)
+	fs.Parse(args)
+	checkCommon()
+	if fs.NArg() == 0 {
+		usageError(fs, "rm needs at least one CONTAINER")
+	}
+	return opts, fs.Args()
+}
+
+// parsePruneFlags reads the arguments of the prune command.
+func parsePruneFlags(args []string) demo.Options {
+	var opts demo.Options
+	fs := newFlagSet("prune", "")
+	checkCommon := addCommonFlags(fs, &opts)
+	fs.StringVar(&opts.PruneUntil, "until", "", "only prune containers and images created before this, a duration such as 24h or a timestamp")
+	fs.Var((*stringSlice)(&opts.PruneLabels), "label", "only prune containers and images with this label, KEY or KEY=VALUE (repeatable)")
+	fs.BoolVar(&opts.PruneVolumes, "volumes", false, "also prune volumes that no container uses")
+	fs.BoolVar(&opts.PruneNetworks, "networks", false, "also prune networks that no container uses, other than the default one; --until and --label do not apply")
+	fs.BoolVar(&opts.PruneAllImages, "all", false, "prune every unused image, not just dangling ones")
+	force := fs.Bool("force", false, "confirm that the pruned objects may be removed")
+	fs.Parse(args)
+	checkCommon()
+	if fs.NArg() > 0 {
+		usageError(fs, fmt.Sprintf("prune takes no arguments, got %q", fs.Arg(0)))
+	}
+	if !*force {
+		usageError(fs, "prune permanently removes containers and images; pass --force to confirm")
+	}
+	return opts
+}
+
+// parseWaitCondition maps a --wait-condition value onto the container
+// state passed to containers.Wait.
+func parseWaitCondition(value string) (define.ContainerStatus, error) {
+	switch value {
+	case "running":
+		return define.ContainerStateRunning, nil
+	case "stopped":
+		return define.ContainerStateStopped, nil
+	case "exited":
+		return define.ContainerStateExited, nil
+	case "paused":
+		return define.ContainerStatePaused, nil
+	}
+	return define.ContainerStateUnknown, fmt.Errorf("unknown --wait-condition %q: must be running, stopped, exited or paused", value)
+}
+
+// parseWaitConditions maps a comma-separated --wait-condition list onto
+// the states passed to containers.Wait, in the order given.
+func parseWaitConditions(value string) ([]define.ContainerStatus, error) {
+	var conditions []define.ContainerStatus
+	for _, v := range strings.Split(value, ",") {
+		condition, err := parseWaitCondition(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range conditions {
+			if c == condition {
+				return nil, fmt.Errorf("--wait-condition lists %s twice", v)
+			}
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// parseLogLevel maps a --log-level value onto a slog level.
+func parseLogLevel(value string) (slog.Level, error) {
+	switch value {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown --log-level %q: must be debug, info, warn or error", value)
+}
+
+// signals maps the signal names accepted by --kill-signal, without their
+// SIG prefix, onto signals.
+var signals = map[string]syscall.Signal{
+	"HUP":   syscall.SIGHUP,
+	"INT":   syscall.SIGINT,
+	"QUIT":  syscall.SIGQUIT,
+	"KILL":  syscall.SIGKILL,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"TERM":  syscall.SIGTERM,
+	"CONT":  syscall.SIGCONT,
+	"STOP":  syscall.SIGSTOP,
+	"WINCH": syscall.SIGWINCH,
+}
+
+// parseSignal accepts a signal name, with or without the SIG prefix, or a
+// signal number.
+func parseSignal(flagName, value string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 1 || n > 64 {
+			return 0, fmt.Errorf("invalid %s %d: must be between 1 and 64", flagName, n)
+		}
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := signals[strings.TrimPrefix(strings.ToUpper(value), "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown %s %q", flagName, value)
+}
+
+// loadConfig sets flags from a YAML or JSON file whose keys are flag
+// names, e.g. "image: fedora" or {"env": ["A=1", "B=2"]}.  Lists set a
+// repeatable flag once per element.  Flags given on the command line are
+// left alone, so they override the file.  Going through fs.Set means
+// the values are parsed and checked exactly like command-line ones.
+func loadConfig(fs *flag.FlagSet, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading --config: %w", err)
+	}
+	// JSON is a subset of YAML, so one parser handles both
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("parsing --config %s: %w", path, err)
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var unknown []string
+	for key := range values {
+		if fs.Lookup(key) == nil || key == "config" {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown keys in --config %s: %s", path, strings.Join(unknown, ", "))
+	}
+
+	for key, value := range values {
+		if explicit[key] {
+			continue
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+		for _, v := range list {
+			if err := fs.Set(key, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("invalid %s in --config %s: %w", key, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// isSet reports whether the flag was given, on the command line or in a
+// --config file, rather than left at its default.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// collectedUsageErrors, when not nil, makes usageError record its message
+// there and return instead of exiting, so that --validate-only can report
+// every problem with the command line at once.
+var collectedUsageErrors *[]string
+
+// usageError prints msg followed by the command's usage message and
+// exits.
+func usageError(fs *flag.FlagSet, msg string) {
+	if collectedUsageErrors != nil {
+		*collectedUsageErrors = append(*collectedUsageErrors, msg)
+		return
+	}
+	if jsonErrors {
+		fail("Parsing the command line", msg, exitUsage)
+	}
+	fmt.Fprintln(fs.Output(), msg)
+	fs.Usage()
+	os.Exit(exitUsage)
+}
+
+// stringSlice is a flag.Value that collects every occurrence of a
+// repeatable flag.
+type stringSlice []string
+
+func (s *stringSlice) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSlice) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/containers/libpod/v2/libpod/define"
+)
+
+func TestParseWaitCondition(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    define.ContainerStatus
+		wantErr bool
+	}{
+		{value: "running", want: define.ContainerStateRunning},
+		{value: "stopped", want: define.ContainerStateStopped},
+		{value: "exited", want: define.ContainerStateExited},
+		{value: "paused", want: define.ContainerStatePaused},
+		{value: "created", wantErr: true},
+		{value: "Running", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseWaitCondition(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseWaitCondition(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseWaitCondition(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseWaitConditions(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []define.ContainerStatus
+		wantErr bool
+	}{
+		{value: "running", want: []define.ContainerStatus{define.ContainerStateRunning}},
+		{value: "exited,stopped", want: []define.ContainerStatus{define.ContainerStateExited, define.ContainerStateStopped}},
+		{value: "paused,running,exited", want: []define.ContainerStatus{define.ContainerStatePaused, define.ContainerStateRunning, define.ContainerStateExited}},
+		{value: "running,bogus", wantErr: true},
+		{value: "exited,exited", wantErr: true},
+		{value: "running,", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseWaitConditions(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseWaitConditions(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseWaitConditions(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseRunFlagsValidateOnly(t *testing.T) {
+	opts := parseRunFlags([]string{"--validate-only", "--wait-condition=running,bogus"})
+	if len(opts.UsageErrors) == 0 {
+		t.Fatal("parseRunFlags recorded no usage errors for --wait-condition=running,bogus")
+	}
+	if opts.WaitCondition != define.ContainerStateUnknown {
+		t.Errorf("WaitCondition = %v, want %v", opts.WaitCondition, define.ContainerStateUnknown)
+	}
+}
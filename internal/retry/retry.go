@@ -0,0 +1,100 @@
+// Package retry calls an operation until it succeeds, waiting between
+// attempts.  The sample uses it wherever the service, a registry or a
+// container might not be ready yet: connecting, pulling and probing.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Policy says how often Do calls the operation and how long it waits in
+// between.
+type Policy struct {
+	// Attempts is the most calls Do makes; zero means no limit, leaving
+	// it to the context.
+	Attempts int
+	// Delay is the wait after the first failure.
+	Delay time.Duration
+	// Multiplier scales the wait after each further failure, e.g. 2 to
+	// double it.  Zero or one keeps the wait constant.
+	Multiplier float64
+	// MaxDelay caps the wait; zero means no cap.
+	MaxDelay time.Duration
+	// Jitter spreads each wait randomly by up to this fraction either
+	// way, e.g. 0.2 for ±20%, so that many clients do not retry in step.
+	Jitter float64
+	// OnRetry, if set, is called after each failed attempt that will be
+	// retried, with its number (from 1), its error and the wait.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// permanentError marks an error that retrying will not fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Do returns it instead of retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// Do calls fn until it returns nil, it returns an error wrapped with
+// Permanent, policy.Attempts calls have been made or ctx is done.  It
+// returns the last error from fn, unwrapped, which is more telling than
+// the context's; callers that need to know check ctx.Err().
+func Do(ctx context.Context, policy Policy, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if (policy.Attempts > 0 && attempt >= policy.Attempts) || ctx.Err() != nil {
+			return err
+		}
+
+		d := policy.delay(attempt)
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, d)
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// delay returns the wait after the given failed attempt, counting from 1.
+func (p Policy) delay(attempt int) time.Duration {
+	d := float64(p.Delay)
+	if p.Multiplier > 1 {
+		for i := 1; i < attempt; i++ {
+			d *= p.Multiplier
+			if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+				break
+			}
+		}
+	}
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
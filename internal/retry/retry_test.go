@@ -0,0 +1,135 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []time.Duration
+	}{
+		{"constant", Policy{Delay: time.Second}, []time.Duration{time.Second, time.Second, time.Second}},
+		{"doubling", Policy{Delay: time.Second, Multiplier: 2}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{"capped", Policy{Delay: time.Second, Multiplier: 3, MaxDelay: 5 * time.Second}, []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second}},
+		{"multiplier below one", Policy{Delay: time.Second, Multiplier: 0.5}, []time.Duration{time.Second, time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.policy.delay(i + 1); got != want {
+					t.Errorf("delay(%d) = %s, want %s", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDelayJitter(t *testing.T) {
+	p := Policy{Delay: time.Second, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if d := p.delay(1); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("delay(1) = %s, want within 20%% of 1s", d)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	errBusy := errors.New("busy")
+	tests := []struct {
+		name       string
+		attempts   int
+		results    []error
+		wantErr    error
+		wantCalls  int
+		wantDelays []time.Duration
+	}{
+		{"first time", 3, []error{nil}, nil, 1, nil},
+		{"after retries", 3, []error{errBusy, errBusy, nil}, nil, 3, []time.Duration{time.Millisecond, 2 * time.Millisecond}},
+		{"out of attempts", 2, []error{errBusy, errBusy, nil}, errBusy, 2, []time.Duration{time.Millisecond}},
+		{"permanent", 3, []error{Permanent(errBusy), nil}, errBusy, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delays []time.Duration
+			policy := Policy{
+				Attempts:   tt.attempts,
+				Delay:      time.Millisecond,
+				Multiplier: 2,
+				OnRetry: func(_ int, _ error, d time.Duration) {
+					delays = append(delays, d)
+				},
+			}
+			calls := 0
+			err := Do(context.Background(), policy, func() error {
+				calls++
+				return tt.results[calls-1]
+			})
+			if err != tt.wantErr {
+				t.Errorf("Do() = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Do() made %d calls, want %d", calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(delays, tt.wantDelays) {
+				t.Errorf("Do() waited %v, want %v", delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestDoWaits(t *testing.T) {
+	start := time.Now()
+	calls := 0
+	Do(context.Background(), Policy{Attempts: 3, Delay: 20 * time.Millisecond, Multiplier: 2}, func() error {
+		calls++
+		return errors.New("busy")
+	})
+	// 20ms after the first failure and 40ms after the second
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Do() returned after %s, want at least 60ms", elapsed)
+	}
+	if calls != 3 {
+		t.Errorf("Do() made %d calls, want 3", calls)
+	}
+}
+
+func TestDoCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errBusy := errors.New("busy")
+	calls := 0
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := Do(ctx, Policy{Delay: time.Hour}, func() error {
+		calls++
+		return errBusy
+	})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Do() returned %s after the cancel, want promptly", elapsed)
+	}
+	if err != errBusy {
+		t.Errorf("Do() = %v, want the last error %v", err, errBusy)
+	}
+	if calls != 1 {
+		t.Errorf("Do() made %d calls, want 1", calls)
+	}
+}
+
+func TestDoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	Do(ctx, Policy{Delay: time.Millisecond}, func() error {
+		calls++
+		return errors.New("busy")
+	})
+	if calls != 1 {
+		t.Errorf("Do() made %d calls with a done context, want 1", calls)
+	}
+}
@@ -0,0 +1,41 @@
+// Package sem bounds how many calls to the Podman service the sample has
+// in flight at once.  One Limiter is shared by every step that makes calls
+// concurrently, such as pulling several images or starting replicas, so
+// that together they cannot overwhelm the service however their own
+// limits are set.
+package sem
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// Limiter admits a fixed number of operations at a time.  A nil Limiter
+// admits any number, so callers need not check whether one was set up.
+type Limiter struct {
+	w *semaphore.Weighted
+}
+
+// New returns a Limiter that admits n operations at a time.  For n less
+// than 1 it returns nil, which admits any number.
+func New(n int) *Limiter {
+	if n < 1 {
+		return nil
+	}
+	return &Limiter{w: semaphore.NewWeighted(int64(n))}
+}
+
+// Do waits for a free slot and calls fn, holding the slot until fn
+// returns.  If ctx is done first, fn is not called and ctx's error is
+// returned.
+func (l *Limiter) Do(ctx context.Context, fn func() error) error {
+	if l == nil {
+		return fn()
+	}
+	if err := l.w.Acquire(ctx, 1); err != nil {
+		return err
+	}
+	defer l.w.Release(1)
+	return fn()
+}
@@ -0,0 +1,97 @@
+package sem
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoBoundsConcurrency(t *testing.T) {
+	const limit, calls = 3, 20
+	l := New(limit)
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Do(context.Background(), func() error {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	if peak > limit {
+		t.Errorf("%d calls ran at once, want at most %d", peak, limit)
+	}
+}
+
+func TestDoReturnsError(t *testing.T) {
+	want := errors.New("boom")
+	if err := New(1).Do(context.Background(), func() error { return want }); err != want {
+		t.Errorf("Do() = %v, want %v", err, want)
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	l := New(1)
+	hold := make(chan struct{})
+	started := make(chan struct{})
+	go l.Do(context.Background(), func() error {
+		close(started)
+		<-hold
+		return nil
+	})
+	<-started
+	defer close(hold)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err := l.Do(ctx, func() error {
+		called = true
+		return nil
+	})
+	if err != context.Canceled || called {
+		t.Errorf("Do() with a canceled context = %v, called %v; want %v, not called", err, called, context.Canceled)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	called := false
+	if err := l.Do(context.Background(), func() error {
+		called = true
+		return nil
+	}); err != nil || !called {
+		t.Errorf("nil Limiter: Do() = %v, called %v; want nil, called", err, called)
+	}
+}
+
+func TestNewUnbounded(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		l := New(n)
+		if l != nil {
+			t.Fatalf("New(%d) = %v, want nil", n, l)
+		}
+		called := false
+		if err := l.Do(context.Background(), func() error {
+			called = true
+			return nil
+		}); err != nil || !called {
+			t.Errorf("New(%d): Do() = %v, called %v; want nil, called", n, err, called)
+		}
+	}
+}
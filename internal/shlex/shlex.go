@@ -0,0 +1,82 @@
+// Package shlex splits a command line into arguments the way a POSIX shell
+// does, for --shell-parse.  Only quoting and escapes are understood: there
+// is no expansion of variables, globs or command substitutions, and
+// operators such as ; or | are ordinary characters.
+package shlex
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnterminated is returned for a quote that is not closed, or a
+// backslash at the very end.
+var ErrUnterminated = errors.New("unterminated quote or escape")
+
+// Split splits s into arguments at unquoted blanks.  Within single quotes
+// every character is literal; within double quotes a backslash only
+// escapes $, `, ", \ and a newline; elsewhere it escapes any character,
+// and a backslash-newline is removed.  Quotes join with the text around
+// them, so a'b c'd is one argument, and a pair of quotes with nothing
+// between them is an empty one.
+func Split(s string) ([]string, error) {
+	var (
+		args  []string
+		arg   strings.Builder
+		inArg bool
+	)
+	r := []rune(s)
+	for i := 0; i < len(r); i++ {
+		c := r[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			i++
+			if i == len(r) {
+				return nil, ErrUnterminated
+			}
+			if r[i] != '\n' {
+				arg.WriteRune(r[i])
+				inArg = true
+			}
+		case c == '\'':
+			end := i + 1
+			for end < len(r) && r[end] != '\'' {
+				end++
+			}
+			if end == len(r) {
+				return nil, ErrUnterminated
+			}
+			arg.WriteString(string(r[i+1 : end]))
+			inArg = true
+			i = end
+		case c == '"':
+			i++
+			for ; i < len(r) && r[i] != '"'; i++ {
+				if r[i] == '\\' && i+1 < len(r) && strings.ContainsRune("$`\"\\\n", r[i+1]) {
+					i++
+					if r[i] == '\n' {
+						continue
+					}
+				}
+				arg.WriteRune(r[i])
+			}
+			if i == len(r) {
+				return nil, ErrUnterminated
+			}
+			inArg = true
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,47 @@
+package shlex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     []string
+	}{
+		{"words", "echo hello world", []string{"echo", "hello", "world"}},
+		{"extra blanks", "  echo \t hi\n", []string{"echo", "hi"}},
+		{"empty", "", nil},
+		{"single quotes", `sh -c 'echo hi'`, []string{"sh", "-c", "echo hi"}},
+		{"double quotes", `echo "a  b"`, []string{"echo", "a  b"}},
+		{"single quotes are literal", `echo '$HOME \n "x"'`, []string{"echo", `$HOME \n "x"`}},
+		{"escapes in double quotes", `echo "say \"hi\" \\ \$HOME \n"`, []string{"echo", `say "hi" \ $HOME \n`}},
+		{"escaped space", `cat my\ file`, []string{"cat", "my file"}},
+		{"escaped quote", `echo it\'s`, []string{"echo", "it's"}},
+		{"line continuation", "echo a\\\nb", []string{"echo", "ab"}},
+		{"quotes join", `a'b c'd"e f"`, []string{"ab cde f"}},
+		{"empty argument", `printf '' x ""`, []string{"printf", "", "x", ""}},
+		{"operators are plain", "a;b | c", []string{"a;b", "|", "c"}},
+		{"unicode", `echo 'héllo wörld'`, []string{"echo", "héllo wörld"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Split(tt.in)
+			if err != nil {
+				t.Fatalf("Split(%q) error = %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitUnterminated(t *testing.T) {
+	for _, in := range []string{`echo 'hi`, `echo "hi`, `echo "hi\"`, `echo hi\`} {
+		if got, err := Split(in); err != ErrUnterminated {
+			t.Errorf("Split(%q) = %q, %v; want ErrUnterminated", in, got, err)
+		}
+	}
+}
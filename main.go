@@ -2,109 +2,114 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/containers/libpod/v2/libpod/define"
-	"github.com/containers/libpod/v2/pkg/bindings"
-	"github.com/containers/libpod/v2/pkg/bindings/containers"
-	"github.com/containers/libpod/v2/pkg/bindings/images"
-	"github.com/containers/libpod/v2/pkg/domain/entities"
-	"github.com/containers/libpod/v2/pkg/specgen"
+	"github.com/lsm5/bindings-sample/pkg/demo"
 )
 
-func main() {
-	fmt.Println("Welcome to Podman Go bindings tutorial")
-
-	// Get Podman socket location
-	sock_dir := os.Getenv("XDG_RUNTIME_DIR")
-	socket := "unix://" + sock_dir + "/podman/podman.sock"
+// Exit codes.  Like the podman CLI's, they let scripts tell a tutorial
+// that never got going from a container that exited non-zero, whose own
+// code is mirrored:
+//
+//	1    a step failed after the container was created (demo.FailureOther)
+//	2    the command line was invalid
+//	125  an option was invalid or the service could not be reached (demo.FailureSetup)
+//	126  the service refused to create the container (demo.FailureNotCreated)
+//	127  the image was not found locally or in the registry (demo.FailureImageNotFound)
+//
+// A run with --validate-only exits 0 when everything checks out, and 3
+// with the list of problems otherwise (demo.FailureInvalid).
+//
+// With --json-errors, a failure other than the container's own is
+// reported on stderr as a single jsonError object, for scripts to parse,
+// rather than as a message.
+const (
+	exitFailure       = 1
+	exitUsage         = 2
+	exitInvalid       = 3
+	exitSetup         = 125
+	exitNotCreated    = 126
+	exitImageNotFound = 127
+)
 
-	// Connect to Podman socket
-	conn, err := bindings.NewConnection(context.Background(), socket)
-	if err != nil {
-		fmt.Println(err)
-		return
+// exitCode maps an error returned by a command, other than a
+// demo.ExitCodeError, to the exit code.
+func exitCode(err error) int {
+	switch demo.FailureOf(err) {
+	case demo.FailureSetup:
+		return exitSetup
+	case demo.FailureNotCreated:
+		return exitNotCreated
+	case demo.FailureImageNotFound:
+		return exitImageNotFound
+	case demo.FailureInvalid:
+		return exitInvalid
 	}
+	return exitFailure
+}
 
-	// Pull image
-	rawImage := "registry.fedoraproject.org/fedora:latest"
-	fmt.Println("Pulling image...")
-	_, err = images.Pull(conn, rawImage, entities.ImagePullOptions{})
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
+// jsonErrors is set by --json-errors.
+var jsonErrors bool
 
-	// List images (WIP)
-	imageSummary, err := images.List(conn, nil, nil)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-	var names []string
-	for _, i := range imageSummary {
-		names = append(names, i.RepoTags...)
-	}
-	fmt.Println(names)
+// jsonError is what --json-errors prints for a failure: the step it
+// happened in, the error message and the code the program exits with.
+type jsonError struct {
+	Step     string `json:"step"`
+	Error    string `json:"error"`
+	ExitCode int    `json:"exit_code"`
+}
 
-	// Container create
-	s := specgen.NewSpecGenerator(rawImage, false)
-	s.Terminal = true
-	r, err := containers.CreateWithSpec(conn, s)
-	if err != nil {
-		fmt.Println(err)
-		return
+// fail reports a failure in step on stderr, as a message or, with
+// --json-errors, as a jsonError, and exits with code.
+func fail(step, msg string, code int) {
+	if jsonErrors {
+		b, _ := json.Marshal(jsonError{Step: step, Error: msg, ExitCode: code})
+		fmt.Fprintln(os.Stderr, string(b))
+	} else {
+		fmt.Fprintln(os.Stderr, "Error:", msg)
 	}
+	os.Exit(code)
+}
 
-	// Container start
-	fmt.Println("Starting Fedora container...")
-	err = containers.Start(conn, r.ID, nil)
-	if err != nil {
-		fmt.Println(err)
-		return
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(exitUsage)
 	}
-
-	// Wait for container to run
-	running := define.ContainerStateRunning
-	_, err = containers.Wait(conn, r.ID, &running)
-	if err != nil {
-		fmt.Println(err)
+	switch os.Args[1] {
+	case "help", "-h", "-help", "--help":
+		usage(os.Stdout)
 		return
 	}
-
-	// List containers
-	var latestContainers = 1
-	containerLatestList, err := containers.List(conn, nil, nil, &latestContainers, nil, nil, nil)
-	if err != nil {
-		fmt.Println(err)
-		return
+	cmd := findCommand(os.Args[1])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(exitUsage)
 	}
-	fmt.Printf("Latest container is %s\n", containerLatestList[0].Names[0])
 
-	// Container inspect
-	ctrData, err := containers.Inspect(conn, r.ID, nil)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-	fmt.Printf("Container uses image %s\n", ctrData.ImageName)
-	fmt.Printf("Container running status is %s\n", ctrData.State.Status)
+	// Cancel the run on Ctrl-C or SIGTERM so the container is not orphaned
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := cmd.run(ctx, os.Args[2:])
+	stop()
 
-	// Container stop
-	fmt.Println("Stopping the container...")
-	err = containers.Stop(conn, r.ID, nil)
-	if err != nil {
-		fmt.Println(err)
-		return
+	// Mirror the container's own exit code when it failed
+	var code demo.ExitCodeError
+	if errors.As(err, &code) {
+		os.Exit(int(code))
 	}
-
-	ctrData, err = containers.Inspect(conn, r.ID, nil)
 	if err != nil {
-		fmt.Println(err)
-		return
+		// Only Run names its steps; for the other commands, the step is
+		// the command
+		step := demo.StepOf(err)
+		if step == "" {
+			step = cmd.name
+		}
+		fail(step, err.Error(), exitCode(err))
 	}
-	fmt.Printf("Container running status is now %s\n", ctrData.State.Status)
-	return
-
 }
@@ -0,0 +1,43 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// attachContainer connects our stdin, stdout and stderr to the container
+// until it exits or the user types the detach keys.
+//
+// When stdin is a terminal and the container has one too, the binding
+// puts our terminal into raw mode and forwards window size changes to the
+// container.  We save the terminal state ourselves as well, so it is put
+// back even if the run is interrupted while attached.  A piped stdin is
+// handled by attachPiped instead.
+func attachContainer(ctx context.Context, conn context.Context, id, detachKeys string) error {
+	if stdinPiped() {
+		if err := attachPiped(ctx, conn, id, detachKeys); err != nil {
+			return fmt.Errorf("attaching to container %s: %w", id, err)
+		}
+		return nil
+	}
+	if fd := int(os.Stdin.Fd()); terminal.IsTerminal(fd) {
+		state, err := terminal.GetState(fd)
+		if err != nil {
+			return fmt.Errorf("saving terminal state: %w", err)
+		}
+		defer terminal.Restore(fd, state)
+	}
+
+	stream := true
+	err := withContext(ctx, func() error {
+		return containers.Attach(conn, id, &detachKeys, nil, &stream, os.Stdin, os.Stdout, os.Stderr, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("attaching to container %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containers/buildah/imagebuildah"
+	"github.com/containers/libpod/v2/pkg/bindings/images"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+	"github.com/containers/storage/pkg/archive"
+)
+
+// DefaultBuildTag names the image built by --build when --tag is not set.
+const DefaultBuildTag = "localhost/bindings-sample:latest"
+
+// buildImage builds the image described by containerfile in the context
+// directory dir and tags it as tag, drawing progress on progress.
+//
+// The service cannot see our filesystem, so the context directory is sent
+// as a tar stream and containerfile must be relative to it.  This version
+// of images.Build prints the build output itself once the build is done
+// rather than line by line.
+func buildImage(ctx context.Context, conn context.Context, dir, containerfile, tag string, progress io.Writer) error {
+	if _, err := os.Stat(filepath.Join(dir, containerfile)); err != nil {
+		return fmt.Errorf("building image: %w", err)
+	}
+	tarfile, err := archive.TarWithOptions(dir, &archive.TarOptions{})
+	if err != nil {
+		return fmt.Errorf("archiving build context %s: %w", dir, err)
+	}
+	defer tarfile.Close()
+
+	buildOpts := entities.BuildOptions{
+		BuildOptions: imagebuildah.BuildOptions{
+			Output: tag,
+		},
+	}
+	err = withProgress(ctx, progress, "Building "+tag, func() error {
+		_, err := images.Build(conn, []string{containerfile}, buildOpts, tarfile)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("building image %s: %w", tag, err)
+	}
+	return nil
+}
+
+// checkBaseImages makes sure every image the containerfile builds FROM is
+// present, for --no-pull.  The build endpoint of this version cannot be
+// told not to pull, so this is the only way to keep a build offline.
+// Stages named with AS and scratch are skipped; a FROM that uses a build
+// argument cannot be checked and is rejected.
+func checkBaseImages(conn context.Context, dir, containerfile string) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, containerfile))
+	if err != nil {
+		return fmt.Errorf("building image: %w", err)
+	}
+	stages := map[string]bool{"scratch": true}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		fields = fields[1:]
+		for len(fields) > 1 && strings.HasPrefix(fields[0], "--") {
+			fields = fields[1:]
+		}
+		base := fields[0]
+		if !stages[strings.ToLower(base)] {
+			if strings.Contains(base, "$") {
+				return fmt.Errorf("%s: cannot check that base image %s is present for --no-pull", containerfile, base)
+			}
+			exists, err := images.Exists(conn, base)
+			if err != nil {
+				return fmt.Errorf("checking for image %s: %w", base, err)
+			}
+			if !exists {
+				return failed(FailureImageNotFound, fmt.Errorf("base image %s not found locally and --no-pull set", base))
+			}
+		}
+		// Later stages may build FROM this one
+		if len(fields) >= 3 && strings.EqualFold(fields[1], "AS") {
+			stages[strings.ToLower(fields[2])] = true
+		}
+	}
+	return nil
+}
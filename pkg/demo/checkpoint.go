@@ -0,0 +1,110 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/containers/libpod/v2/pkg/bindings"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+)
+
+// The containers.Checkpoint and containers.Restore bindings pass the
+// archive path as the export and import parameters, but the service takes
+// those as booleans and sends or expects the archive in the request body
+// instead.  We call the endpoints directly through the bindings'
+// connection so the archive actually reaches the host.
+
+// checkpointContainer checkpoints the running container into an archive
+// at path and returns the archive's size.  The container is stopped
+// afterwards, unless leaveRunning is set.
+func checkpointContainer(conn context.Context, id, path string, leaveRunning bool) (int64, error) {
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return 0, err
+	}
+	params := url.Values{}
+	params.Set("export", "true")
+	params.Set("leaveRunning", strconv.FormatBool(leaveRunning))
+	response, err := client.DoRequest(nil, http.MethodPost, "/containers/%s/checkpoint", params, nil, id)
+	if err != nil {
+		return 0, fmt.Errorf("checkpointing container %s: %w", id, err)
+	}
+	if !response.IsSuccess() {
+		return 0, fmt.Errorf("checkpointing container %s: %w", id, explainCRIU(response.Process(nil)))
+	}
+	defer response.Body.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(f, response.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("writing checkpoint to %s: %w", path, err)
+	}
+	return size, nil
+}
+
+// restoreContainer restores the checkpointed container from the archive
+// at path and returns the restored container's ID.  This version of the
+// service restores into the existing container named in the URL, which
+// is why the checkpoint has to come from this run.
+//
+// Given a name, Podman 3.0 and later instead create a new container
+// called name from the archive, as a migration would on the target host,
+// and leave the checkpointed one be.  It keeps its addresses, so the new
+// one does not ask for the same static IP and MAC.
+func restoreContainer(conn context.Context, id, path, name string) (string, error) {
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	params := url.Values{}
+	params.Set("import", "true")
+	if name != "" {
+		params.Set("name", name)
+		params.Set("ignoreStaticIP", "true")
+		params.Set("ignoreStaticMAC", "true")
+	}
+	response, err := client.DoRequest(f, http.MethodPost, "/containers/%s/restore", params, nil, id)
+	if err != nil {
+		return "", fmt.Errorf("restoring container %s: %w", id, err)
+	}
+	var report entities.RestoreReport
+	if err := response.Process(&report); err != nil {
+		return "", fmt.Errorf("restoring container %s from %s: %w", id, path, explainCRIU(err))
+	}
+	return report.Id, nil
+}
+
+// explainCRIU adds a hint to errors caused by CRIU, which does the actual
+// checkpointing, being missing or too old on the service host, and to a
+// --restore-name that is taken.
+func explainCRIU(err error) error {
+	if err == nil {
+		return err
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "already in use"):
+		return fmt.Errorf("%w (pick a --restore-name no other container has)", err)
+	case !strings.Contains(msg, "criu"):
+		return err
+	}
+	return fmt.Errorf("%w (checkpoint and restore need CRIU installed where the service runs, and a rootful service)", err)
+}
@@ -0,0 +1,58 @@
+package demo
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// cleanupStack records what a Run creates, so that it can be torn down in
+// the reverse order: replicas, container, pod, network, volume, manifest
+// list and image.  Removing anything earlier would fail with "in use".
+type cleanupStack struct {
+	steps []cleanupStep
+}
+
+// cleanupStep undoes one thing Run did.  step is what it does, such as
+// "removing the pod", and done what it did.  Steps with a kept message
+// only run if the run is interrupted or --rm is set; the others always
+// run.
+type cleanupStep struct {
+	step, done, kept string
+	undo             func() error
+}
+
+// push records a resource that --rm removes, such as "pod", with the
+// message printed when it is kept instead.
+func (s *cleanupStack) push(what, kept string, undo func() error) {
+	s.steps = append(s.steps, cleanupStep{step: "removing the " + what, done: "Removed the " + what, kept: kept, undo: undo})
+}
+
+// pushAlways records a step that runs however the run ends.
+func (s *cleanupStack) pushAlways(step, done string, undo func() error) {
+	s.steps = append(s.steps, cleanupStep{step: step, done: done, undo: undo})
+}
+
+// unwind runs the steps, last first.  A failing step is reported and the
+// rest still run.  The bindings ignore the context's cancellation, so the
+// connection can still be used after an interrupt.
+func (s *cleanupStack) unwind(interrupted, remove bool, logger *slog.Logger) {
+	for i := len(s.steps) - 1; i >= 0; i-- {
+		st := s.steps[i]
+		switch {
+		case st.kept != "" && interrupted:
+			logger.Info(fmt.Sprintf("Interrupted, %s...", st.step))
+		case st.kept == "" || remove:
+			logger.Info(strings.ToUpper(st.step[:1]) + st.step[1:] + "...")
+		default:
+			logger.Info("Keeping " + st.kept)
+			continue
+		}
+		if err := st.undo(); err != nil {
+			fmt.Fprintln(os.Stderr, "Cleanup:", err)
+			continue
+		}
+		logger.Info(st.done)
+	}
+}
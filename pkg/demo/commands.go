@@ -0,0 +1,129 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+)
+
+// The functions in this file back the bindings-sample subcommands other
+// than run.  Each performs a single step of the tutorial on its own.
+
+// commandOutput returns the narrative writer and logger for a subcommand,
+// honouring the log level the same way Run does.
+func commandOutput(level slog.Level) (io.Writer, *slog.Logger) {
+	out := io.Writer(os.Stdout)
+	logger := newLogger(out, level)
+	if level > slog.LevelInfo {
+		out = io.Discard
+	}
+	return out, logger
+}
+
+// Pull pulls opts.Image, retrying transient failures.
+func Pull(ctx context.Context, opts Options) error {
+	out, logger := commandOutput(opts.LogLevel)
+	conn, err := connect(ctx, opts, out, logger)
+	if err != nil {
+		return err
+	}
+	pullOpts := pullOptions(opts)
+	logger.Info(fmt.Sprintf("Pulling image %s...", opts.Image))
+	logger.Debug("images.Pull", "image", opts.Image, "authfile", pullOpts.Authfile,
+		"username", pullOpts.Username, "password", maskPassword(pullOpts.Password))
+	var ids []string
+	err = withPullTimeout(ctx, "pulling "+opts.Image, opts.PullTimeout, func(ctx context.Context) error {
+		var err error
+		ids, err = PullImage(ctx, conn, opts.Image, pullOpts, opts.PullRetries, opts.PullBackoff, logger, progressWriter(opts))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("pulling image %s: %w", opts.Image, err)
+	}
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+	return nil
+}
+
+// Build builds the image in the opts.Build context directory and names it
+// opts.Tag.
+func Build(ctx context.Context, opts Options) error {
+	out, logger := commandOutput(opts.LogLevel)
+	conn, err := connect(ctx, opts, out, logger)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Building image %s from %s...", opts.Tag, opts.Build))
+	return withPullTimeout(ctx, "building "+opts.Tag, opts.PullTimeout, func(ctx context.Context) error {
+		return buildImage(ctx, conn, opts.Build, opts.Containerfile, opts.Tag, progressWriter(opts))
+	})
+}
+
+// List prints a table of the containers matching opts.ContainerFilters,
+// or with opts.Format one line per container.
+func List(ctx context.Context, opts Options) error {
+	out, logger := commandOutput(opts.LogLevel)
+	filters, err := parseFilters("--filter", opts.ContainerFilters)
+	if err != nil {
+		return err
+	}
+	format, err := parseFormat("--format", opts.Format)
+	if err != nil {
+		return err
+	}
+	conn, err := connect(ctx, opts, out, logger)
+	if err != nil {
+		return err
+	}
+	var last *int
+	if opts.ListLimit > 0 {
+		last = &opts.ListLimit
+	}
+	logger.Debug("containers.List", "filters", filters, "all", opts.All, "last", opts.ListLimit)
+	list, err := containers.List(conn, filters, &opts.All, last, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+	if format != nil {
+		return printContainersFormatted(os.Stdout, format, list)
+	}
+	printContainerTable(os.Stdout, list)
+	return nil
+}
+
+// RemoveContainers stops and removes the named containers.  It carries on
+// past failures, reporting each one on stderr.
+func RemoveContainers(ctx context.Context, opts Options, names []string) error {
+	out, logger := commandOutput(opts.LogLevel)
+	conn, err := connect(ctx, opts, out, logger)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, name := range names {
+		// removeContainer ignores missing containers, which rm should not
+		exists, err := containers.Exists(conn, name)
+		if err == nil && !exists {
+			err = fmt.Errorf("no such container %s", name)
+		}
+		if err == nil {
+			logger.Debug("containers.Remove", "id", name)
+			err = removeContainer(conn, name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			failed++
+			continue
+		}
+		fmt.Println(name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d containers could not be removed", failed, len(names))
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package demo
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/containers/libpod/v2/pkg/bindings"
+	"github.com/containers/libpod/v2/pkg/bindings/system"
+	"github.com/lsm5/bindings-sample/internal/retry"
+)
+
+// minServerVersion is the oldest Podman service this sample is written
+// against.  Older services may lack some of the endpoints it uses.
+const minServerVersion = "2.0.0"
+
+// rootfulSocket is where the Podman service listens when run as root.
+const rootfulSocket = "/run/podman/podman.sock"
+
+// ResolveSocket returns the URI of the Podman service to connect to, and
+// the SSH identity file for it when the URI came from containers.conf.
+// Like the podman CLI, the first of these that is set wins:
+//
+//  1. explicit, from --socket
+//  2. the CONTAINER_HOST environment variable; the bindings read its
+//     identity from CONTAINER_SSHKEY themselves
+//  3. the containers.conf connection named by CONTAINER_CONNECTION
+//  4. the containers.conf active_service connection
+//  5. the local socket: the rootless socket under XDG_RUNTIME_DIR for
+//     regular users, followed by the rootful socket
+func ResolveSocket(explicit string) (uri, identity string, err error) {
+	if explicit != "" {
+		return explicit, "", nil
+	}
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host, "", nil
+	}
+
+	active, destinations, err := readServiceDestinations()
+	if err != nil {
+		return "", "", err
+	}
+	if name := os.Getenv("CONTAINER_CONNECTION"); name != "" {
+		d, ok := destinations[name]
+		if !ok {
+			return "", "", fmt.Errorf("CONTAINER_CONNECTION %q is not a connection in containers.conf", name)
+		}
+		return d.URI, d.Identity, nil
+	}
+	if d, ok := destinations[active]; ok && active != "" {
+		return d.URI, d.Identity, nil
+	}
+
+	candidates := localSockets()
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return "unix://" + path, "", nil
+		}
+	}
+	return "", "", fmt.Errorf("%w (tried %v); start the service with `podman system service`", errNoSocket, candidates)
+}
+
+// errNoSocket is returned by ResolveSocket when it falls back to the local
+// sockets and none of them exists.
+var errNoSocket = errors.New("no Podman socket found")
+
+// localSockets lists the sockets a local service listens on, in the order
+// ResolveSocket tries them: the rootless socket under XDG_RUNTIME_DIR for
+// regular users, followed by the rootful socket.
+func localSockets() []string {
+	var sockets []string
+	if os.Geteuid() != 0 {
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			sockets = append(sockets, filepath.Join(dir, "podman", "podman.sock"))
+		}
+	}
+	return append(sockets, rootfulSocket)
+}
+
+// waitForSocket polls for the socket file of a unix:// URI to appear, for
+// up to wait, as it may not on a fresh boot while the service is still
+// starting.  Other URIs are returned straight away.
+func waitForSocket(ctx context.Context, uri string, wait time.Duration) error {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "unix" {
+		return nil
+	}
+	// unix://run/podman/podman.sock puts "run" in the host; the bindings
+	// put it back the same way
+	path := u.Path
+	if !strings.HasPrefix(uri, "unix:///") {
+		path = "/" + u.Host + u.Path
+	}
+
+	deadline, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	err = retry.Do(deadline, retry.Policy{Delay: 250 * time.Millisecond}, func() error {
+		_, err := os.Stat(path)
+		return err
+	})
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	}
+	unit := "systemctl --user start podman.socket"
+	if os.Geteuid() == 0 {
+		unit = "systemctl start podman.socket"
+	}
+	return fmt.Errorf("socket %s did not appear within %s; start the service with `%s`", path, wait, unit)
+}
+
+// serviceDestination is a named connection in containers.conf, as added
+// by podman system connection add.
+type serviceDestination struct {
+	URI      string `toml:"uri"`
+	Identity string `toml:"identity"`
+}
+
+// containersConf is the part of containers.conf that names connections.
+type containersConf struct {
+	Engine struct {
+		ActiveService       string                        `toml:"active_service"`
+		ServiceDestinations map[string]serviceDestination `toml:"service_destinations"`
+	} `toml:"engine"`
+}
+
+// containersConfPaths lists the containers.conf files podman reads, with
+// later files overriding earlier ones.  CONTAINERS_CONF replaces them all.
+func containersConfPaths() []string {
+	if path := os.Getenv("CONTAINERS_CONF"); path != "" {
+		return []string{path}
+	}
+	paths := []string{"/usr/share/containers/containers.conf", "/etc/containers/containers.conf"}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		paths = append(paths, filepath.Join(dir, "containers", "containers.conf"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "containers", "containers.conf"))
+	}
+	return paths
+}
+
+// readServiceDestinations returns the active connection and all named
+// connections from containers.conf.  Missing files are skipped.
+func readServiceDestinations() (string, map[string]serviceDestination, error) {
+	var active string
+	destinations := make(map[string]serviceDestination)
+	for _, path := range containersConfPaths() {
+		var conf containersConf
+		if _, err := toml.DecodeFile(path, &conf); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if conf.Engine.ActiveService != "" {
+			active = conf.Engine.ActiveService
+		}
+		for name, d := range conf.Engine.ServiceDestinations {
+			destinations[name] = d
+		}
+	}
+	return active, destinations, nil
+}
+
+// Connect calls bindings.NewConnectionWithIdentity up to attempts times,
+// doubling the delay between tries, so a service that is still starting up
+// gets a chance to come up.  The whole loop is bounded by timeout.  It
+// returns the first working connection or the last error seen.  SSH
+// authentication failures are not retried, since they will not go away.
+// A non-nil tlsConfig connects to a tcp:// URI over TLS.
+func Connect(ctx context.Context, uri, identity string, tlsConfig *tls.Config, attempts int, timeout time.Duration, log io.Writer) (context.Context, error) {
+	deadline, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	policy := retry.Policy{
+		Attempts:   attempts,
+		Delay:      250 * time.Millisecond,
+		Multiplier: 2,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			fmt.Fprintf(log, "Connection attempt %d/%d failed: %v; retrying in %s\n", attempt, attempts, err, delay)
+		},
+	}
+	var conn context.Context
+	err := retry.Do(deadline, policy, func() error {
+		err := withContext(deadline, func() error {
+			var err error
+			if tlsConfig != nil {
+				conn, err = newTLSConnection(ctx, uri, tlsConfig)
+			} else {
+				conn, err = bindings.NewConnectionWithIdentity(ctx, uri, identity)
+			}
+			return err
+		})
+		if isSSHAuthError(err) {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// checkService asks the service for its version, which confirms that the
+// socket really is a Podman service, and warns if it is older than
+// minServerVersion.  It returns the version.
+func checkService(conn context.Context, out io.Writer) (string, error) {
+	report, err := system.Version(conn)
+	if err != nil {
+		return "", fmt.Errorf("querying the service version: %w", err)
+	}
+	if report.Server == nil {
+		return "", fmt.Errorf("the service did not report its version; is it a Podman service?")
+	}
+	fmt.Fprintf(out, "Connected to Podman %s on %s (client %s)\n", report.Server.Version, report.Server.OsArch, report.Client.Version)
+
+	if olderThan(report.Server.Version, minServerVersion) {
+		fmt.Fprintf(os.Stderr, "Warning: Podman %s is older than %s; some steps may fail\n", report.Server.Version, minServerVersion)
+	}
+	return report.Server.Version, nil
+}
+
+// serviceVersionKey is the key of the service's version in a connection
+// made by connectService.
+type serviceVersionKey struct{}
+
+// serviceVersion returns the version of the service conn is connected to,
+// or "" if conn was not made by connectService.
+func serviceVersion(conn context.Context) string {
+	version, _ := conn.Value(serviceVersionKey{}).(string)
+	return version
+}
+
+// olderThan compares two dotted version strings numerically.  Anything
+// after a "-" (such as "-dev" or "-rc1") is ignored.
+func olderThan(version, min string) bool {
+	v := strings.Split(strings.SplitN(version, "-", 2)[0], ".")
+	m := strings.Split(min, ".")
+	for i := range m {
+		var a int
+		if i < len(v) {
+			a, _ = strconv.Atoi(v[i])
+		}
+		b, _ := strconv.Atoi(m[i])
+		if a != b {
+			return a < b
+		}
+	}
+	return false
+}
+
+// connect finds the socket to use, connects to it and checks the service
+// version, as every command does before anything else.  Its failures are
+// FailureSetup.
+func connect(ctx context.Context, opts Options, out io.Writer, logger *slog.Logger) (context.Context, error) {
+	conn, err := connectService(ctx, opts, out, logger)
+	return conn, failed(FailureSetup, err)
+}
+
+// connectService does the work of connect.
+func connectService(ctx context.Context, opts Options, out io.Writer, logger *slog.Logger) (context.Context, error) {
+	socket, identity, err := ResolveSocket(opts.Socket)
+	if errors.Is(err, errNoSocket) && opts.SocketWait > 0 {
+		// Wait for the socket that would have been used first
+		socket, err = "unix://"+localSockets()[0], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.SocketWait > 0 {
+		logger.Debug("waiting for socket", "uri", socket, "wait", opts.SocketWait)
+		if err := waitForSocket(ctx, socket, opts.SocketWait); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Identity != "" {
+		identity = opts.Identity
+	}
+	if err := checkURI(socket, identity); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := loadTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTCP(socket, tlsConfig, opts.Insecure); err != nil {
+		return nil, err
+	}
+	logger.Debug("bindings.NewConnectionWithIdentity", "uri", socket, "identity", identity, "tls", tlsConfig != nil, "retries", opts.ConnectRetries, "timeout", opts.ConnectTimeout)
+	conn, err := Connect(ctx, socket, identity, tlsConfig, opts.ConnectRetries, opts.ConnectTimeout, out)
+	if err != nil {
+		return nil, explainConnectError(socket, err)
+	}
+	version, err := checkService(conn, out)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(conn, serviceVersionKey{}, version), nil
+}
+
+// checkURI rejects URIs the bindings cannot connect to, before any attempt
+// is made, and an identity file given for anything but ssh://.
+func checkURI(uri, identity string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("invalid service URI %q: %w", uri, err)
+	}
+	switch u.Scheme {
+	case "unix":
+	case "tcp":
+		if u.Host == "" {
+			return fmt.Errorf("invalid service URI %q: expected tcp://HOST:PORT", uri)
+		}
+	case "ssh":
+		if u.User == nil || u.Hostname() == "" || u.Path == "" {
+			return fmt.Errorf("invalid service URI %q: expected ssh://USER@HOST[:PORT]/PATH/TO/podman.sock", uri)
+		}
+	default:
+		return fmt.Errorf("unsupported service URI %q: use unix://, ssh:// or tcp://", uri)
+	}
+	if identity != "" && u.Scheme != "ssh" {
+		return fmt.Errorf("--identity only applies to ssh:// URIs, not %q", uri)
+	}
+	return nil
+}
+
+// isSSHAuthError reports whether err is the SSH server, or the identity
+// file, turning us away, as opposed to the connection itself failing.
+// The bindings only wrap the ssh package's errors as text, so match on it.
+func isSSHAuthError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "unable to authenticate") || strings.Contains(msg, "failed to parse identity")
+}
+
+// explainConnectError adds a hint for the common ways of failing to reach
+// the service, telling SSH authentication apart from a refused connection.
+func explainConnectError(uri string, err error) error {
+	switch {
+	case isSSHAuthError(err):
+		return fmt.Errorf("SSH authentication to %s failed; check --identity, CONTAINER_SSHKEY or ssh-agent: %w", uri, err)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("connection to %s refused; is the host up and the service listening?: %w", uri, err)
+	}
+	return fmt.Errorf("connecting to %s: %w", uri, err)
+}
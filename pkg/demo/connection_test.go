@@ -0,0 +1,125 @@
+package demo
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSocket(t *testing.T) {
+	const conf = `
+[engine]
+active_service = "prod"
+
+[engine.service_destinations.prod]
+uri = "ssh://core@prod.example.com/run/podman/podman.sock"
+identity = "/keys/prod"
+
+[engine.service_destinations.dev]
+uri = "ssh://core@dev.example.com/run/user/1000/podman/podman.sock"
+identity = "/keys/dev"
+`
+	// The rootless socket is only tried by regular users, so as root the
+	// run falls through to the rootful socket, if there is one
+	_, rootfulErr := os.Stat(rootfulSocket)
+	local := func(rootless string) (string, error) {
+		switch {
+		case os.Geteuid() != 0 && rootless != "":
+			return "unix://" + rootless, nil
+		case rootfulErr == nil:
+			return "unix://" + rootfulSocket, nil
+		}
+		return "", errNoSocket
+	}
+
+	tests := []struct {
+		name         string
+		explicit     string
+		env          map[string]string
+		conf         string
+		local        bool // fall through to the local sockets
+		socket       bool // create the rootless socket
+		want, wantID string
+		wantErr      bool
+	}{
+		{
+			name:     "explicit",
+			explicit: "unix:///tmp/podman.sock",
+			env:      map[string]string{"CONTAINER_HOST": "tcp://localhost:8080"},
+			conf:     conf,
+			want:     "unix:///tmp/podman.sock",
+		},
+		{
+			name: "CONTAINER_HOST",
+			env:  map[string]string{"CONTAINER_HOST": "tcp://localhost:8080", "CONTAINER_CONNECTION": "dev"},
+			conf: conf,
+			want: "tcp://localhost:8080",
+		},
+		{
+			name:   "CONTAINER_CONNECTION",
+			env:    map[string]string{"CONTAINER_CONNECTION": "dev"},
+			conf:   conf,
+			want:   "ssh://core@dev.example.com/run/user/1000/podman/podman.sock",
+			wantID: "/keys/dev",
+		},
+		{
+			name:    "unknown CONTAINER_CONNECTION",
+			env:     map[string]string{"CONTAINER_CONNECTION": "staging"},
+			conf:    conf,
+			wantErr: true,
+		},
+		{
+			name:   "active service",
+			conf:   conf,
+			want:   "ssh://core@prod.example.com/run/podman/podman.sock",
+			wantID: "/keys/prod",
+		},
+		{name: "local socket", local: true, socket: true},
+		{name: "no socket", local: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("CONTAINER_HOST", "")
+			t.Setenv("CONTAINER_CONNECTION", "")
+			t.Setenv("XDG_RUNTIME_DIR", dir)
+			t.Setenv("CONTAINERS_CONF", filepath.Join(dir, "containers.conf"))
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if tt.conf != "" {
+				if err := ioutil.WriteFile(filepath.Join(dir, "containers.conf"), []byte(tt.conf), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, wantErr := tt.want, error(nil)
+			if tt.local {
+				var rootless string
+				if tt.socket {
+					rootless = filepath.Join(dir, "podman", "podman.sock")
+					if err := os.MkdirAll(filepath.Dir(rootless), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := ioutil.WriteFile(rootless, nil, 0600); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, wantErr = local(rootless)
+			}
+
+			uri, identity, err := ResolveSocket(tt.explicit)
+			switch {
+			case wantErr != nil:
+				if !errors.Is(err, wantErr) {
+					t.Errorf("ResolveSocket(%q) error = %v, want %v", tt.explicit, err, wantErr)
+				}
+			case (err != nil) != tt.wantErr:
+				t.Errorf("ResolveSocket(%q) error = %v, wantErr %v", tt.explicit, err, tt.wantErr)
+			case err == nil && (uri != want || identity != tt.wantID):
+				t.Errorf("ResolveSocket(%q) = %q, %q; want %q, %q", tt.explicit, uri, identity, want, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,705 @@
+package demo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/libpod/v2/libpod/define"
+	"github.com/containers/libpod/v2/pkg/bindings"
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	"github.com/containers/libpod/v2/pkg/bindings/images"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+	"github.com/containers/libpod/v2/pkg/specgen"
+	"github.com/containers/storage/pkg/archive"
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// createContainer creates the container described by s, with the given
+// extras.  When replace is set, an existing container with the same name
+// is removed first, so that rerunning the tutorial ends in the same state.
+func createContainer(conn context.Context, s *specgen.SpecGenerator, extras specExtras, replace bool, out io.Writer) (entities.ContainerCreateResponse, error) {
+	var r entities.ContainerCreateResponse
+	if replace && s.Name != "" {
+		if err := replaceContainer(conn, s.Name, out); err != nil {
+			return r, failed(FailureNotCreated, err)
+		}
+	}
+	r, err := createWithSpec(conn, s, extras)
+	if err != nil && isNameInUse(err) {
+		return r, failed(FailureNotCreated, fmt.Errorf("container name %q is already in use; remove that container or pass --replace", s.Name))
+	}
+	if err != nil {
+		return r, failed(FailureNotCreated, fmt.Errorf("creating container: %w", err))
+	}
+	return r, nil
+}
+
+// specExtras are container settings that came after this version of the
+// SpecGenerator, which has no fields for them.  createWithSpec adds them
+// to the spec it posts; services older than Podman 2.2 ignore the aliases,
+// and those older than 3.0 the image volumes and the sd_notify mode.
+type specExtras struct {
+	// aliases are the --network-alias names, by network name.
+	aliases map[string][]string
+	// imageVolumes are the --mount-type type=image mounts.
+	imageVolumes []imageVolume
+	// sdNotifyMode is --sdnotify when it is container or ignore; conmon
+	// is the service's default.
+	sdNotifyMode string
+	// secrets are the --secret secrets, mounted as files.
+	secrets []string
+	// secretEnv are the --secret-env secrets, by environment variable.
+	secretEnv map[string]string
+	// dependencies are the --requires containers, and the one made by
+	// --create-dependency.
+	dependencies []string
+}
+
+// buildExtras works out the specExtras a run asks for.  The options have
+// already been checked by buildSpec.
+func buildExtras(opts Options) (specExtras, error) {
+	var extras specExtras
+	if len(opts.NetworkAliases) > 0 {
+		extras.aliases = map[string][]string{opts.Network: opts.NetworkAliases}
+	}
+	if opts.SdNotify != "conmon" {
+		extras.sdNotifyMode = opts.SdNotify
+	}
+	extras.dependencies = opts.Requires
+	extras.secrets = opts.Secrets
+	var err error
+	if extras.secretEnv, err = parseSecretEnv(opts.SecretEnv); err != nil {
+		return extras, err
+	}
+	_, _, extras.imageVolumes, err = parseMountTypes(opts.MountTypes)
+	return extras, err
+}
+
+// createWithSpec is containers.CreateWithSpec, except that an empty
+// entrypoint and the extras reach the service.
+//
+// A nil s.Entrypoint means "use the image's", while an empty one means
+// "none at all"; but the field is omitempty, so the bindings drop an
+// empty entrypoint on the way and the service falls back to the image's.
+// When either that or an extra is needed we post the spec ourselves, with
+// the fields put in.
+func createWithSpec(conn context.Context, s *specgen.SpecGenerator, extras specExtras) (entities.ContainerCreateResponse, error) {
+	var r entities.ContainerCreateResponse
+	emptyEntrypoint := s.Entrypoint != nil && len(s.Entrypoint) == 0
+	if !emptyEntrypoint && len(extras.aliases) == 0 && len(extras.imageVolumes) == 0 && extras.sdNotifyMode == "" && len(extras.secrets) == 0 && len(extras.secretEnv) == 0 && len(extras.dependencies) == 0 {
+		return containers.CreateWithSpec(conn, s)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return r, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return r, err
+	}
+	if emptyEntrypoint {
+		fields["entrypoint"] = json.RawMessage("[]")
+	}
+	if len(extras.aliases) > 0 {
+		if fields["aliases"], err = json.Marshal(extras.aliases); err != nil {
+			return r, err
+		}
+	}
+	if len(extras.imageVolumes) > 0 {
+		if fields["image_volumes"], err = json.Marshal(extras.imageVolumes); err != nil {
+			return r, err
+		}
+	}
+	if extras.sdNotifyMode != "" {
+		if fields["sdnotifyMode"], err = json.Marshal(extras.sdNotifyMode); err != nil {
+			return r, err
+		}
+	}
+	if len(extras.secrets) > 0 {
+		if fields["secrets"], err = json.Marshal(secretsField(serviceVersion(conn), extras.secrets)); err != nil {
+			return r, err
+		}
+	}
+	if len(extras.secretEnv) > 0 {
+		if fields["secret_env"], err = json.Marshal(extras.secretEnv); err != nil {
+			return r, err
+		}
+	}
+	if len(extras.dependencies) > 0 {
+		if fields["dependencyContainers"], err = json.Marshal(extras.dependencies); err != nil {
+			return r, err
+		}
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return r, err
+	}
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return r, err
+	}
+	response, err := client.DoRequest(bytes.NewReader(data), http.MethodPost, "/containers/create", nil, nil)
+	if err != nil {
+		return r, err
+	}
+	return r, response.Process(&r)
+}
+
+// checkDevices makes sure that the host side of each --device exists.  That
+// can only be done when the service runs on this host, over a unix socket;
+// otherwise a missing device is reported when the container is created.
+func checkDevices(conn context.Context, devices []spec.LinuxDevice) error {
+	if len(devices) == 0 {
+		return nil
+	}
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return err
+	}
+	if client.URI.Scheme != "unix" {
+		return nil
+	}
+	for _, d := range devices {
+		host := strings.SplitN(d.Path, ":", 2)[0]
+		if _, err := os.Stat(host); err != nil {
+			return fmt.Errorf("invalid --device %q: %w", d.Path, err)
+		}
+	}
+	return nil
+}
+
+// checkNamespaceContainers makes sure that the containers whose namespaces
+// the spec joins exist, so that a typo is reported as such rather than as
+// a create failure.
+func checkNamespaceContainers(conn context.Context, s *specgen.SpecGenerator) error {
+	for _, ns := range []struct {
+		name string
+		ns   specgen.Namespace
+	}{
+		{"cgroup", s.CgroupNS},
+		{"PID", s.PidNS},
+		{"IPC", s.IpcNS},
+		{"UTS", s.UtsNS},
+	} {
+		if !ns.ns.IsContainer() {
+			continue
+		}
+		exists, err := containers.Exists(conn, ns.ns.Value)
+		if err != nil {
+			return fmt.Errorf("checking for container %s: %w", ns.ns.Value, err)
+		}
+		if !exists {
+			return fmt.Errorf("cannot join the %s namespace of container %s: no such container", ns.name, ns.ns.Value)
+		}
+	}
+	return nil
+}
+
+// checkRequires makes sure that the --requires containers exist, so that
+// a typo is reported as such rather than as a create failure.
+func checkRequires(conn context.Context, requires []string) error {
+	for _, name := range requires {
+		exists, err := containers.Exists(conn, name)
+		if err != nil {
+			return fmt.Errorf("checking for container %s: %w", name, err)
+		}
+		if !exists {
+			return fmt.Errorf("invalid --requires %q: no such container", name)
+		}
+	}
+	return nil
+}
+
+// createDependency creates the --create-dependency container, from the
+// same image as s and in the same pod, but does not start it: starting
+// the container that requires it does.  It only sleeps.
+func createDependency(conn context.Context, s *specgen.SpecGenerator) (string, error) {
+	d := specgen.NewSpecGenerator(s.Image, false)
+	d.Pod = s.Pod
+	d.Command = []string{"sleep", "infinity"}
+	r, err := containers.CreateWithSpec(conn, d)
+	if err != nil {
+		return "", fmt.Errorf("creating the dependency container: %w", err)
+	}
+	return r.ID, nil
+}
+
+// printDependencies shows the state of each container the tutorial's
+// requires, which Podman started, if need be, just before it.
+func printDependencies(conn context.Context, requires []string, out io.Writer) error {
+	for _, name := range requires {
+		data, err := containers.Inspect(conn, name, nil)
+		if err != nil {
+			return fmt.Errorf("inspecting dependency %s: %w", name, err)
+		}
+		fmt.Fprintf(out, "Dependency %s (%.12s) is %s\n", strings.TrimPrefix(data.Name, "/"), data.ID, data.State.Status)
+	}
+	return nil
+}
+
+// replaceContainer removes the container called name, if there is one.
+func replaceContainer(conn context.Context, name string, out io.Writer) error {
+	exists, err := containers.Exists(conn, name)
+	if err != nil {
+		return fmt.Errorf("checking for container %s: %w", name, err)
+	}
+	if !exists {
+		return nil
+	}
+	fmt.Fprintf(out, "Replacing existing container %s...\n", name)
+	return removeContainer(conn, name)
+}
+
+// isNameInUse reports whether a create failed because another container
+// already has the requested name.  The service does not use a distinct
+// status code for this, so we have to look at the message.
+func isNameInUse(err error) bool {
+	return strings.Contains(err.Error(), "already in use")
+}
+
+// errRenameUnsupported is returned by renameContainer when the service
+// has no rename endpoint.
+var errRenameUnsupported = errors.New("the service does not support renaming containers; Podman 3.0 or later is required")
+
+// renameContainer gives the container a new name and checks the result
+// via inspect.
+//
+// containers.Rename does not exist in this version of the bindings, so we
+// call the rename endpoint directly through the bindings' connection.
+func renameContainer(conn context.Context, id, name string, out io.Writer) error {
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("name", name)
+	response, err := client.DoRequest(nil, http.MethodPost, "/containers/%s/rename", params, nil, id)
+	if err != nil {
+		return fmt.Errorf("renaming container to %s: %w", name, err)
+	}
+	if err := response.Process(nil); err != nil {
+		var apiErr entities.ErrorModel
+		switch {
+		case errors.As(err, &apiErr) && apiErr.Code() == http.StatusConflict, isNameInUse(err):
+			return fmt.Errorf("cannot rename container: the name %s is already in use", name)
+		case strings.Contains(err.Error(), "is not supported"):
+			return errRenameUnsupported
+		}
+		return fmt.Errorf("renaming container to %s: %w", name, err)
+	}
+
+	data, err := containers.Inspect(conn, id, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting container: %w", err)
+	}
+	if data.Name != name {
+		return fmt.Errorf("container is still named %s after renaming it to %s", data.Name, name)
+	}
+	fmt.Fprintf(out, "Container is now named %s\n", data.Name)
+	return nil
+}
+
+// removeContainer stops and removes the container, treating a container
+// that is already stopped or gone as success so it is safe to call more
+// than once.
+func removeContainer(conn context.Context, id string) error {
+	if err := containers.Stop(conn, id, nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("stopping container %s: %w", id, err)
+	}
+	force := true
+	if err := containers.Remove(conn, id, &force, nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("removing container %s: %w", id, err)
+	}
+	return nil
+}
+
+// pauseContainer pauses the container for d, checking the state reported
+// by inspect after each transition.  The container is unpaused even when
+// the wait is interrupted, so that cleanup finds it running.
+func pauseContainer(ctx, conn context.Context, id string, d time.Duration, out io.Writer) error {
+	if err := containers.Pause(conn, id); err != nil {
+		return fmt.Errorf("pausing container: %w", err)
+	}
+	if err := expectState(conn, id, "paused", out); err != nil {
+		return err
+	}
+
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+
+	if err := containers.Unpause(conn, id); err != nil {
+		return fmt.Errorf("unpausing container: %w", err)
+	}
+	if err := expectState(conn, id, "running", out); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// restartContainer stops the container, giving it timeout seconds to exit,
+// and starts it again in one call, then waits for it to be running.  The
+// start time reported by inspect confirms that it really was restarted.
+func restartContainer(conn context.Context, id string, timeout int, out io.Writer) error {
+	before, err := containers.Inspect(conn, id, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting container %s: %w", id, err)
+	}
+	if err := containers.Restart(conn, id, &timeout); err != nil {
+		return fmt.Errorf("restarting container %s: %w", id, err)
+	}
+	running := define.ContainerStateRunning
+	if _, err := containers.Wait(conn, id, &running); err != nil {
+		return fmt.Errorf("waiting for container %s to be running again: %w", id, err)
+	}
+
+	after, err := containers.Inspect(conn, id, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting container %s: %w", id, err)
+	}
+	if !after.State.StartedAt.After(before.State.StartedAt) {
+		return fmt.Errorf("container %s was not restarted: still started at %s", id, after.State.StartedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(out, "Container was restarted at %s and is %s\n", after.State.StartedAt.Format(time.RFC3339), after.State.Status)
+	return nil
+}
+
+// expectState inspects the container and fails unless it is in state.
+func expectState(conn context.Context, id, state string, out io.Writer) error {
+	data, err := containers.Inspect(conn, id, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting container: %w", err)
+	}
+	if data.State.Status != state {
+		return fmt.Errorf("container is %s, expected %s", data.State.Status, state)
+	}
+	fmt.Fprintf(out, "Container is now %s\n", data.State.Status)
+	return nil
+}
+
+// checkCIDFile makes sure a --cidfile can be written, before anything is
+// created: its directory must exist, and the file must not unless force
+// is set.
+func checkCIDFile(path string, force bool) error {
+	if err := checkSave("--cidfile", path); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("--cidfile %s already exists; remove it or pass --force", path)
+	}
+	return nil
+}
+
+// writeCIDFile writes the container's ID to path, as podman run --cidfile
+// does: the ID alone, without a newline.  Unless force is set, a file that
+// appeared since checkCIDFile is left alone.
+func writeCIDFile(path, id string, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("writing --cidfile: %w", err)
+	}
+	_, err = f.WriteString(id)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("writing --cidfile %s: %w", path, err)
+	}
+	return nil
+}
+
+// exportContainer writes the container's filesystem as a tar archive to
+// path and returns the archive's size.
+func exportContainer(conn context.Context, id, path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	err = containers.Export(conn, id, w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("exporting container to %s: %w", path, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// commitContainer commits the container to a new image named ref and
+// checks that the image shows up in the image list.
+func commitContainer(conn context.Context, id, ref string, opts Options, out io.Writer) (string, error) {
+	repo, tag := splitReference(ref)
+	commitOpts := containers.CommitOptions{
+		Repo:    &repo,
+		Tag:     &tag,
+		Changes: opts.CommitChanges,
+	}
+	if opts.CommitAuthor != "" {
+		commitOpts.Author = &opts.CommitAuthor
+	}
+	if opts.CommitMessage != "" {
+		commitOpts.Comment = &opts.CommitMessage
+	}
+	resp, err := containers.Commit(conn, id, commitOpts)
+	if err != nil {
+		return "", fmt.Errorf("committing container to %s: %w", ref, err)
+	}
+
+	found, err := images.List(conn, nil, map[string][]string{"reference": {repo + ":" + tag}})
+	if err != nil {
+		return "", fmt.Errorf("listing images: %w", err)
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("committed image %s is missing from the image list", ref)
+	}
+	fmt.Fprintf(out, "Image %s:%s is listed with ID %.12s\n", repo, tag, found[0].ID)
+	return resp.ID, nil
+}
+
+// checkImportTag makes sure that ref, the --container-to-image name, is an
+// image reference with no digest: an imported image only gets a digest
+// once it is pushed.
+func checkImportTag(ref string) error {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return fmt.Errorf("invalid --container-to-image %q: %w", ref, err)
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return fmt.Errorf("invalid --container-to-image %q: an imported image cannot be given a digest", ref)
+	}
+	return nil
+}
+
+// containerToImage exports the container's filesystem and imports it as
+// a new image named ref, with the Containerfile instructions in changes
+// applied, then checks that the image shows up in the image list.  Unlike
+// a commit, the image is a single flattened layer with none of the
+// original image's configuration, unless changes put it back.
+//
+// The archive is piped from the export straight into the import, so it is
+// never held in memory or written to disk, however big the container is.
+func containerToImage(conn context.Context, id, ref string, changes []string, out io.Writer) (string, error) {
+	r, w := io.Pipe()
+	exported := make(chan error, 1)
+	go func() {
+		err := containers.Export(conn, id, w)
+		w.CloseWithError(err)
+		exported <- err
+	}()
+	report, err := images.Import(conn, changes, nil, &ref, nil, r)
+	// Stops the export if the import gave up part way
+	r.Close()
+	exportErr := <-exported
+	switch {
+	case exportErr != nil && !errors.Is(exportErr, io.ErrClosedPipe):
+		return "", fmt.Errorf("exporting container: %w", exportErr)
+	case err != nil:
+		return "", fmt.Errorf("importing container as %s: %w", ref, err)
+	}
+
+	repo, tag := splitReference(ref)
+	found, err := images.List(conn, nil, map[string][]string{"reference": {repo + ":" + tag}})
+	if err != nil {
+		return "", fmt.Errorf("listing images: %w", err)
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("imported image %s is missing from the image list", ref)
+	}
+	fmt.Fprintf(out, "Image %s:%s is listed with ID %.12s\n", repo, tag, found[0].ID)
+	return report.Id, nil
+}
+
+// printDiff prints the paths the container added, changed and deleted,
+// grouped by kind.  Kinds without changes are left out.
+func printDiff(conn context.Context, id string, out io.Writer) error {
+	changes, err := containers.Diff(conn, id)
+	if err != nil {
+		return fmt.Errorf("diffing container: %w", err)
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "Container filesystem is unchanged")
+		return nil
+	}
+
+	byKind := make(map[archive.ChangeType][]string)
+	for _, c := range changes {
+		byKind[c.Kind] = append(byKind[c.Kind], c.Path)
+	}
+	for _, group := range []struct {
+		kind  archive.ChangeType
+		title string
+	}{
+		{archive.ChangeAdd, "Added"},
+		{archive.ChangeModify, "Changed"},
+		{archive.ChangeDelete, "Deleted"},
+	} {
+		paths := byKind[group.kind]
+		if len(paths) == 0 {
+			continue
+		}
+		sort.Strings(paths)
+		fmt.Fprintf(out, "%s:\n", group.title)
+		for _, p := range paths {
+			fmt.Fprintf(out, "  %s\n", p)
+		}
+	}
+	return nil
+}
+
+// printTop prints the container's process table using the given ps
+// descriptors.  A container that is no longer running has no processes to
+// show, which is reported rather than treated as an error.
+func printTop(conn context.Context, id string, descriptors []string, out io.Writer) error {
+	lines, err := containers.Top(conn, id, descriptors)
+	if err != nil {
+		var apiErr entities.ErrorModel
+		if errors.As(err, &apiErr) && apiErr.Code() == http.StatusConflict || strings.Contains(err.Error(), "not running") {
+			fmt.Fprintln(out, "Container is no longer running, so there are no processes to show")
+			return nil
+		}
+		return fmt.Errorf("listing container processes: %w", err)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	return w.Flush()
+}
+
+// killExitTimeout bounds how long killContainer waits for the container
+// to exit after signalling it.
+const killExitTimeout = 10 * time.Second
+
+// killContainer sends sig to the container's main process and waits for
+// it to exit.  Not every signal is fatal, so a container that is still
+// running after killExitTimeout is stopped instead.
+func killContainer(conn context.Context, id string, sig syscall.Signal, out io.Writer) error {
+	if err := containers.Kill(conn, id, strconv.Itoa(int(sig))); err != nil {
+		var apiErr entities.ErrorModel
+		if errors.As(err, &apiErr) && apiErr.Code() == http.StatusConflict {
+			fmt.Fprintln(out, "Container is not running, so there is nothing to kill")
+			return nil
+		}
+		return fmt.Errorf("killing container with %s: %w", sig, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), killExitTimeout)
+	defer cancel()
+	exited := define.ContainerStateExited
+	err := withContext(ctx, func() error {
+		_, err := containers.Wait(conn, id, &exited)
+		return err
+	})
+	if ctx.Err() != nil {
+		fmt.Fprintf(out, "Container is still running %s after %s, stopping it...\n", killExitTimeout, sig)
+		err = containers.Stop(conn, id, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("waiting for container to exit: %w", err)
+	}
+	return nil
+}
+
+// mountContainer mounts the container's root filesystem and returns its
+// path on the service host.
+//
+// A rootless service mounts it inside the user namespace it runs in, so
+// the path is only visible from the host through podman unshare, and the
+// podman CLI refuses to mount without it.
+func mountContainer(conn context.Context, id string, out io.Writer) (string, error) {
+	path, err := containers.Mount(conn, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "unshare") || strings.Contains(err.Error(), "rootless") {
+			return "", fmt.Errorf("mounting container %s: %w (rootless containers can only be mounted inside the user namespace; run this under podman unshare)", id, err)
+		}
+		return "", fmt.Errorf("mounting container %s: %w", id, err)
+	}
+	fmt.Fprintf(out, "Container root filesystem is mounted at %s\n", path)
+	if _, err := os.Stat(path); err != nil && os.Geteuid() != 0 {
+		fmt.Fprintf(out, "It lives in the rootless user namespace; look at it with: podman unshare ls %s\n", path)
+	}
+	return path, nil
+}
+
+// waitContainer waits until the container reaches any of the conditions
+// and returns the one it reached, along with its exit code.
+//
+// containers.Wait only takes a single condition in this version of the
+// bindings, so several are sent to the wait endpoint directly, which
+// returns as soon as one of them holds.  Services older than Podman 4.0
+// only look at the last one.  The endpoint does not say which condition
+// was met, so the container is inspected afterwards to find out.
+func waitContainer(conn context.Context, id string, conditions []define.ContainerStatus) (define.ContainerStatus, int32, error) {
+	if len(conditions) == 1 {
+		exitCode, err := containers.Wait(conn, id, &conditions[0])
+		return conditions[0], exitCode, err
+	}
+
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return define.ContainerStateUnknown, 0, err
+	}
+	params := url.Values{}
+	for _, c := range conditions {
+		params.Add("condition", c.String())
+	}
+	response, err := client.DoRequest(nil, http.MethodPost, "/containers/%s/wait", params, nil, id)
+	if err != nil {
+		return define.ContainerStateUnknown, 0, err
+	}
+	var exitCode int32
+	if err := response.Process(&exitCode); err != nil {
+		return define.ContainerStateUnknown, 0, err
+	}
+
+	data, err := containers.Inspect(conn, id, nil)
+	if err != nil {
+		return define.ContainerStateUnknown, 0, fmt.Errorf("inspecting container %s: %w", id, err)
+	}
+	state, err := define.StringToContainerStatus(data.State.Status)
+	if err != nil {
+		return define.ContainerStateUnknown, 0, err
+	}
+	for _, c := range conditions {
+		if c == state {
+			return c, exitCode, nil
+		}
+	}
+	// A container that has exited is also stopped, and vice versa
+	for _, c := range conditions {
+		if (c == define.ContainerStateExited || c == define.ContainerStateStopped) &&
+			(state == define.ContainerStateExited || state == define.ContainerStateStopped) {
+			return c, exitCode, nil
+		}
+	}
+	return state, exitCode, nil
+}
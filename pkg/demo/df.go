@@ -0,0 +1,69 @@
+package demo
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+)
+
+// diskUsage is one row of podman system df: how much space the images,
+// containers or volumes take, and how much removing the unused ones would
+// free.
+type diskUsage struct {
+	Type        string `json:"type"`
+	Total       int    `json:"total"`
+	Active      int    `json:"active"`
+	Size        int64  `json:"size"`
+	Reclaimable int64  `json:"reclaimable"`
+}
+
+// summarizeDiskUsage totals the system df report the way podman does.  An
+// image is active while a container uses it, a container while it runs
+// and a volume while a container mounts it; only the others count as
+// reclaimable.  Containers are sized by their writable layer.
+func summarizeDiskUsage(report *entities.SystemDfReport) []diskUsage {
+	imageRow := diskUsage{Type: "Images", Total: len(report.Images)}
+	for _, img := range report.Images {
+		imageRow.Size += img.Size
+		if img.Containers > 0 {
+			imageRow.Active++
+		} else {
+			imageRow.Reclaimable += img.Size
+		}
+	}
+	ctrRow := diskUsage{Type: "Containers", Total: len(report.Containers)}
+	for _, ctr := range report.Containers {
+		ctrRow.Size += ctr.RWSize
+		if ctr.Status == "running" {
+			ctrRow.Active++
+		} else {
+			ctrRow.Reclaimable += ctr.RWSize
+		}
+	}
+	volRow := diskUsage{Type: "Local Volumes", Total: len(report.Volumes)}
+	for _, vol := range report.Volumes {
+		volRow.Size += vol.Size
+		if vol.Links > 0 {
+			volRow.Active++
+		} else {
+			volRow.Reclaimable += vol.Size
+		}
+	}
+	return []diskUsage{imageRow, ctrRow, volRow}
+}
+
+// printDiskUsage prints the rows like podman system df.
+func printDiskUsage(out io.Writer, rows []diskUsage) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tTOTAL\tACTIVE\tSIZE\tRECLAIMABLE")
+	for _, r := range rows {
+		percent := 0
+		if r.Size > 0 {
+			percent = int(100 * r.Reclaimable / r.Size)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s (%d%%)\n", r.Type, r.Total, r.Active, humanSize(r.Size), humanSize(r.Reclaimable), percent)
+	}
+	w.Flush()
+}
@@ -0,0 +1,167 @@
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stepEvent is one line of the --events-log file.
+type stepEvent struct {
+	Step   string            `json:"step"`
+	Start  time.Time         `json:"start"`
+	End    time.Time         `json:"end"`
+	Status string            `json:"status"`
+	Error  string            `json:"error,omitempty"`
+	IDs    map[string]string `json:"ids,omitempty"`
+}
+
+// cleanupPrefixes start the messages of the steps that Run defers until
+// the end, such as "Removing the container...".
+var cleanupPrefixes = []string{"Interrupted, ", "Removing ", "Unmounting "}
+
+// eventLog wraps a handler and writes each step announced at info level,
+// the same steps stepRecorder sees, as a JSON line for --events-log.  A
+// step ends when the next one starts, and its line is written straight to
+// the file then, so a run that crashes still leaves the steps it finished.
+//
+// The one exception is the step that was running when the cleanup began:
+// whether it failed is only known once Run returns, so close writes it.
+type eventLog struct {
+	slog.Handler
+	state *eventLogState
+}
+
+type eventLogState struct {
+	mu      sync.Mutex
+	file    *os.File
+	enc     *json.Encoder
+	current *stepEvent
+	pending *stepEvent
+	cleanup bool
+	err     error
+}
+
+func newEventLog(h slog.Handler, path string) (*eventLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating --events-log: %w", err)
+	}
+	return &eventLog{Handler: h, state: &eventLogState{file: f, enc: json.NewEncoder(f)}}, nil
+}
+
+func (h *eventLog) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *eventLog) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level == slog.LevelInfo && strings.HasSuffix(r.Message, "...") {
+		h.state.begin(strings.TrimSuffix(r.Message, "..."), r.Time)
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *eventLog) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &eventLog{Handler: h.Handler.WithAttrs(attrs), state: h.state}
+}
+
+func (h *eventLog) WithGroup(name string) slog.Handler {
+	return &eventLog{Handler: h.Handler.WithGroup(name), state: h.state}
+}
+
+// produced records an ID, such as the container's, against the current
+// step.  It does nothing on a nil eventLog, so Run can call it whether or
+// not --events-log was given.
+func (h *eventLog) produced(kind, id string) {
+	if h == nil {
+		return
+	}
+	s := h.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return
+	}
+	if s.current.IDs == nil {
+		s.current.IDs = make(map[string]string)
+	}
+	s.current.IDs[kind] = id
+}
+
+// close ends the last steps with the result of the run and closes the
+// file.  It does nothing on a nil eventLog.
+func (h *eventLog) close(runErr error) error {
+	if h == nil {
+		return nil
+	}
+	s := h.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, msg := "ok", ""
+	switch {
+	case errors.Is(runErr, context.Canceled):
+		status, msg = "interrupted", runErr.Error()
+	case runErr != nil:
+		status, msg = "error", runErr.Error()
+	}
+	err := s.err
+	if s.current != nil {
+		s.current.End = time.Now()
+		s.current.Status = "ok"
+		if !s.cleanup {
+			s.current.Status, s.current.Error = status, msg
+		}
+		if werr := s.enc.Encode(s.current); err == nil {
+			err = werr
+		}
+	}
+	if s.pending != nil {
+		s.pending.Status, s.pending.Error = status, msg
+		if werr := s.enc.Encode(s.pending); err == nil {
+			err = werr
+		}
+	}
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// begin ends the current step and starts the next.  A write error is
+// kept for close to report, so that the run itself carries on.
+func (s *eventLogState) begin(step string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil {
+		s.current.End = t
+		if !s.cleanup && isCleanupStep(step) {
+			s.cleanup = true
+			s.pending = s.current
+		} else {
+			s.current.Status = "ok"
+			if err := s.enc.Encode(s.current); err != nil && s.err == nil {
+				s.err = err
+			}
+		}
+	}
+	s.current = &stepEvent{Step: step, Start: t}
+}
+
+func isCleanupStep(step string) bool {
+	for _, prefix := range cleanupPrefixes {
+		if strings.HasPrefix(step, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/containers/libpod/v2/pkg/bindings/system"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+)
+
+// watchEvents prints the events of container id in the background until
+// the returned function is called.  Events since the given time are
+// replayed first, so the subscription may start after the container was
+// created.  If the connection drops, the subscription is made again on a
+// new one, from the time of the last event printed.
+func watchEvents(streams *redialer, id string, since time.Time, out io.Writer) (stop func()) {
+	ctx, stopped := context.WithCancel(context.Background())
+	cancel := make(chan bool)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		var last int64
+		err := streams.follow(ctx, "events", func(conn context.Context) error {
+			// Events closes the channel when it returns, so every
+			// subscription needs its own
+			events := make(chan entities.Event)
+			errc := make(chan error, 1)
+			go func() {
+				sinceArg := since.Format(time.RFC3339Nano)
+				if last != 0 {
+					sinceArg = time.Unix(0, last).Format(time.RFC3339Nano)
+				}
+				stream := true
+				filters := map[string][]string{"container": {id}}
+				errc <- system.Events(conn, events, cancel, &sinceArg, nil, filters, &stream)
+			}()
+			for e := range events {
+				// A resumed subscription replays the last event
+				if e.TimeNano <= last {
+					continue
+				}
+				last = e.TimeNano
+				fmt.Fprintf(out, "Event %s: %s %s\n", time.Unix(0, e.TimeNano).Format(time.RFC3339), e.Type, e.Action)
+			}
+			return <-errc
+		})
+		// Closing cancel makes the read fail; that is not worth reporting
+		if err != nil && ctx.Err() == nil {
+			fmt.Fprintln(os.Stderr, "Watching events:", err)
+		}
+	}()
+
+	return func() {
+		stopped()
+		close(cancel)
+		<-done
+	}
+}
+
+// exitCodeFromEvents returns the exit code of container id, taken from the
+// died event the service recorded for it since the given time.  It is for a
+// container that is no longer there to be waited for or inspected.
+func exitCodeFromEvents(conn context.Context, id string, since time.Time) (int32, error) {
+	events := make(chan entities.Event)
+	errc := make(chan error, 1)
+	go func() {
+		sinceArg := since.Format(time.RFC3339Nano)
+		untilArg := time.Now().Format(time.RFC3339Nano)
+		stream := false
+		filters := map[string][]string{"container": {id}, "event": {"died"}}
+		errc <- system.Events(conn, events, nil, &sinceArg, &untilArg, filters, &stream)
+	}()
+	var (
+		exitCode int32
+		found    bool
+	)
+	for e := range events {
+		if code, err := strconv.Atoi(e.Actor.Attributes["containerExitCode"]); err == nil {
+			exitCode, found = int32(code), true
+		}
+	}
+	if err := <-errc; err != nil {
+		return 0, fmt.Errorf("reading the events of container %s: %w", id, err)
+	}
+	if !found {
+		return 0, fmt.Errorf("no died event for container %s", id)
+	}
+	return exitCode, nil
+}
@@ -0,0 +1,72 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/containers/libpod/v2/libpod/define"
+	"github.com/containers/libpod/v2/pkg/api/handlers"
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	docker "github.com/docker/docker/api/types"
+)
+
+// nopWriteCloser turns an io.Writer into the io.WriteCloser that
+// define.AttachStreams expects.  The bindings never close the streams.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// runExec runs cmd inside the running container, streaming its output to
+// stdout and stderr, and returns the exit code of the exec session.  When
+// stdin is not nil, the command reads it, up to end-of-file.
+func runExec(ctx context.Context, conn context.Context, id string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	// Create the exec session; it does not run until started
+	config := &handlers.ExecCreateConfig{
+		ExecConfig: docker.ExecConfig{
+			Cmd:          cmd,
+			AttachStdin:  stdin != nil,
+			AttachStdout: true,
+			AttachStderr: true,
+		},
+	}
+	sessionID, err := containers.ExecCreate(conn, id, config)
+	if err != nil {
+		return 0, fmt.Errorf("creating exec session: %w", err)
+	}
+
+	// Start the session and stay attached until the command finishes.
+	// ExecStartAndAttach would never close the command's stdin, so with
+	// input we start it ourselves; see hijack.
+	if stdin != nil {
+		var w inputConn
+		var output io.ReadCloser
+		w, output, err = hijack(conn, strings.NewReader(`{"Detach":false}`), "/exec/%s/start", nil, nil, sessionID)
+		if err == nil {
+			err = streamIO(ctx, w, output, false, stdin, stdout, stderr)
+		}
+	} else {
+		streams := &define.AttachStreams{
+			OutputStream: nopWriteCloser{stdout},
+			ErrorStream:  nopWriteCloser{stderr},
+			AttachOutput: true,
+			AttachError:  true,
+		}
+		err = withContext(ctx, func() error {
+			return containers.ExecStartAndAttach(conn, sessionID, streams)
+		})
+	}
+	if err != nil {
+		return 0, fmt.Errorf("running exec session %s: %w", sessionID, err)
+	}
+
+	// The exit code is only available by inspecting the finished session
+	session, err := containers.ExecInspect(conn, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("inspecting exec session %s: %w", sessionID, err)
+	}
+	return session.ExitCode, nil
+}
@@ -0,0 +1,99 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+)
+
+// attachExisting runs the steps that act on a running container against
+// one the sample did not create: attach or follow the logs, exec, top and
+// stats.  Nothing is pushed onto the cleanup stack, so the container is
+// left as it was found.
+func attachExisting(ctx, conn context.Context, nameOrID string, opts Options, logger *slog.Logger, out, ctrOut io.Writer) error {
+	streams := newRedialer(conn, opts, logger)
+	logger.Debug("containers.Exists", "name", nameOrID)
+	exists, err := containers.Exists(conn, nameOrID)
+	if err != nil {
+		return fmt.Errorf("checking for container %s: %w", nameOrID, err)
+	}
+	if !exists {
+		return fmt.Errorf("container %s does not exist", nameOrID)
+	}
+	logger.Debug("containers.Inspect", "name", nameOrID)
+	data, err := containers.Inspect(conn, nameOrID, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting container %s: %w", nameOrID, err)
+	}
+	id := data.ID
+	logger.Info(fmt.Sprintf("Found container %s (%.12s), which is %s", strings.TrimPrefix(data.Name, "/"), id, data.State.Status))
+	if !data.State.Running && (opts.Attach || len(opts.Exec) > 0 || opts.Top || opts.Stats > 0) {
+		return fmt.Errorf("container %s is %s; attaching, exec, top and stats need it running", nameOrID, data.State.Status)
+	}
+
+	// Attach, or follow the logs while the steps below run and stop after
+	// them; a running container's stream would otherwise only end with it
+	finishLogs := func() {}
+	if opts.Attach {
+		logger.Info(fmt.Sprintf("Attaching to the container, detach with %s...", opts.DetachKeys))
+		if err := attachContainer(ctx, conn, id, opts.DetachKeys); err != nil {
+			return err
+		}
+	} else {
+		logsCtx, stopLogs := context.WithCancel(ctx)
+		logsErr := make(chan error, 1)
+		go func() {
+			logsErr <- streamLogs(logsCtx, streams, id, logOptions(opts), ctrOut, os.Stderr)
+		}()
+		finishLogs = func() {
+			stopLogs()
+			if err := <-logsErr; err != nil && logsCtx.Err() == nil {
+				logger.Warn(fmt.Sprintf("could not stream logs of container %.12s: %v", id, err))
+			}
+			finishLogs = func() {}
+		}
+		defer func() { finishLogs() }()
+	}
+
+	if len(opts.Exec) > 0 {
+		logger.Info(fmt.Sprintf("Running %v in the container...", opts.Exec))
+		execCode, err := runExec(ctx, conn, id, opts.Exec, execInput(), ctrOut, os.Stderr)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Exec session exited with code %d", execCode))
+	}
+	if opts.Top {
+		logger.Info("Listing the container's processes...")
+		if err := printTop(conn, id, strings.Split(opts.TopDescriptors, ","), out); err != nil {
+			return err
+		}
+	}
+	if opts.Stats > 0 {
+		logger.Info(fmt.Sprintf("Collecting stats for %s...", opts.Stats))
+		if err := collectStats(ctx, streams, id, opts.Stats, out); err != nil {
+			return err
+		}
+	}
+
+	finishLogs()
+	data, err = containers.Inspect(conn, id, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting container %s: %w", id, err)
+	}
+	opts.Image = data.ImageName
+	printContainer(out, data, opts)
+	if opts.InspectOut != "" {
+		if err := writeInspect(opts.InspectOut, data, opts.Force); err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Wrote the full inspect data to %s", opts.InspectOut))
+	}
+	logger.Info("Leaving the container as it was; it was not created by this run")
+	return nil
+}
@@ -0,0 +1,147 @@
+package demo
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// feature is an option that needs a newer service than minServerVersion.
+// Podman's API versions follow its releases, so the minimum is the first
+// Podman release with the endpoint or field the option relies on.
+type feature struct {
+	// flags names the options, as the user gave them.
+	flags string
+	// min is the oldest Podman service that supports them.
+	min string
+	// used reports whether the run asks for the feature.
+	used func(opts Options) bool
+	// skip turns the feature off, leaving the rest of the run as it was.
+	skip func(opts *Options)
+}
+
+// features are the options an older service would reject, or silently
+// get wrong, with an error that does not say why.
+var features = []feature{
+	{
+		flags: "--generate-systemd",
+		min:   "2.1.0",
+		used:  func(opts Options) bool { return opts.GenerateSystemd },
+		skip:  func(opts *Options) { opts.GenerateSystemd = false },
+	},
+	{
+		flags: "--network-alias",
+		min:   "2.2.0",
+		used:  func(opts Options) bool { return len(opts.NetworkAliases) > 0 },
+		skip:  func(opts *Options) { opts.NetworkAliases = nil },
+	},
+	{
+		flags: "--requires and --create-dependency",
+		min:   "2.2.0",
+		used:  func(opts Options) bool { return len(opts.Requires) > 0 || opts.CreateDependency },
+		skip:  func(opts *Options) { opts.Requires, opts.CreateDependency = nil, false },
+	},
+	{
+		flags: "--rename",
+		min:   "3.0.0",
+		used:  func(opts Options) bool { return opts.Rename != "" },
+		skip:  func(opts *Options) { opts.Rename = "" },
+	},
+	{
+		flags: "--mount-type type=image",
+		min:   "3.0.0",
+		used:  func(opts Options) bool { return len(imageMounts(opts.MountTypes)) > 0 },
+		skip: func(opts *Options) {
+			images := imageMounts(opts.MountTypes)
+			var kept []string
+			for _, e := range opts.MountTypes {
+				if !hasOption(images, e) {
+					kept = append(kept, e)
+				}
+			}
+			opts.MountTypes = kept
+		},
+	},
+	{
+		flags: "--restore-name",
+		min:   "3.0.0",
+		used:  func(opts Options) bool { return opts.RestoreName != "" },
+		// Restoring into the checkpointed container instead only works
+		// once it has stopped
+		skip: func(opts *Options) {
+			if opts.LeaveRunning {
+				opts.Restore = ""
+			}
+			opts.RestoreName = ""
+		},
+	},
+	{
+		flags: "--sdnotify=container and --sdnotify=ignore",
+		min:   "3.0.0",
+		used:  func(opts Options) bool { return opts.SdNotify == "container" || opts.SdNotify == "ignore" },
+		skip:  func(opts *Options) { opts.SdNotify = "conmon" },
+	},
+	{
+		flags: "--secret and --create-secret",
+		min:   "3.1.0",
+		used:  func(opts Options) bool { return len(opts.Secrets) > 0 || len(opts.CreateSecrets) > 0 },
+		skip:  func(opts *Options) { opts.Secrets, opts.CreateSecrets = nil, nil },
+	},
+	{
+		flags: "several --wait-condition states",
+		min:   "4.0.0",
+		used:  func(opts Options) bool { return len(opts.WaitConditions) > 1 },
+		// The steps after the wait assume the first already
+		skip: func(opts *Options) { opts.WaitConditions = nil },
+	},
+	{
+		flags: "--secret-env",
+		min:   "4.0.0",
+		used:  func(opts Options) bool { return len(opts.SecretEnv) > 0 },
+		skip:  func(opts *Options) { opts.SecretEnv = nil },
+	},
+	{
+		flags: "--update-memory and --update-cpus",
+		min:   "4.3.0",
+		used:  func(opts Options) bool { return opts.UpdateMemory != "" || opts.UpdateCPUs != 0 },
+		skip:  func(opts *Options) { opts.UpdateMemory, opts.UpdateCPUs = "", 0 },
+	},
+}
+
+// imageMounts returns the --mount-type entries of type image.
+func imageMounts(entries []string) []string {
+	var images []string
+	for _, e := range entries {
+		if fields, err := parseMountFields(e); err == nil && fields["type"] == "image" {
+			images = append(images, e)
+		}
+	}
+	return images
+}
+
+// checkFeatures compares what opts asks for with what a service of the
+// given version supports, before anything is created.  With
+// --strict-features, an unsupported feature is an error that names every
+// one; otherwise each is skipped with a warning and the run goes on
+// without it.  An unknown version is not checked.
+func checkFeatures(version string, opts *Options, logger *slog.Logger) error {
+	if version == "" {
+		return nil
+	}
+	var missing []string
+	for _, f := range features {
+		if !f.used(*opts) || !olderThan(version, f.min) {
+			continue
+		}
+		if opts.StrictFeatures {
+			missing = append(missing, fmt.Sprintf("  %s needs Podman %s or later", f.flags, f.min))
+			continue
+		}
+		logger.Warn(fmt.Sprintf("skipping %s: it needs Podman %s or later, and the service is %s", f.flags, f.min, version))
+		f.skip(opts)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the service, Podman %s, does not support everything this run asks for (--strict-features):\n%s", version, strings.Join(missing, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package demo
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckFeatures(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	asked := Options{
+		Rename:       "web",
+		MountTypes:   []string{"type=tmpfs,target=/run", "type=image,source=fedora,target=/img"},
+		UpdateMemory: "64m",
+		SdNotify:     "conmon",
+	}
+
+	t.Run("new enough", func(t *testing.T) {
+		opts := asked
+		if err := checkFeatures("4.3.1", &opts, logger); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(opts, asked) {
+			t.Errorf("options changed to %+v", opts)
+		}
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		opts := asked
+		if err := checkFeatures("", &opts, logger); err != nil || !reflect.DeepEqual(opts, asked) {
+			t.Errorf("checkFeatures = %v, options %+v; want nothing checked", err, opts)
+		}
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		opts := asked
+		if err := checkFeatures("3.4.0", &opts, logger); err != nil {
+			t.Fatal(err)
+		}
+		want := asked
+		want.UpdateMemory = ""
+		if !reflect.DeepEqual(opts, want) {
+			t.Errorf("options = %+v, want %+v", opts, want)
+		}
+	})
+
+	t.Run("skipped on an old service", func(t *testing.T) {
+		opts := asked
+		if err := checkFeatures("2.0.4", &opts, logger); err != nil {
+			t.Fatal(err)
+		}
+		if opts.Rename != "" || opts.UpdateMemory != "" {
+			t.Errorf("Rename = %q, UpdateMemory = %q, want both skipped", opts.Rename, opts.UpdateMemory)
+		}
+		if want := []string{"type=tmpfs,target=/run"}; !reflect.DeepEqual(opts.MountTypes, want) {
+			t.Errorf("MountTypes = %q, want %q", opts.MountTypes, want)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		opts := asked
+		opts.StrictFeatures = true
+		err := checkFeatures("2.0.4", &opts, logger)
+		if err == nil {
+			t.Fatal("checkFeatures succeeded, expected an error")
+		}
+		for _, flag := range []string{"--rename", "--mount-type type=image", "--update-memory"} {
+			if !strings.Contains(err.Error(), flag) {
+				t.Errorf("error %q does not mention %s", err, flag)
+			}
+		}
+		if opts.Rename != asked.Rename {
+			t.Error("--strict-features skipped --rename")
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package demo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+	"github.com/docker/go-units"
+)
+
+// containerRow is what a --format template sees for each container.  The
+// fields follow the podman ps --format names.
+type containerRow struct {
+	ID        string
+	Image     string
+	Command   string
+	CreatedAt string
+	Status    string
+	State     string
+	Names     string
+	Ports     string
+	Labels    string
+	Pod       string
+}
+
+// imageRow is what an --image-format template sees for each image tag.
+// The fields follow the podman images --format names.
+type imageRow struct {
+	ID           string
+	Repository   string
+	Tag          string
+	Digest       string
+	CreatedAt    string
+	CreatedSince string
+	Size         string
+}
+
+// parseFormat parses a --format or --image-format template.  Like podman,
+// each item is printed on its own line whether or not the template ends
+// with a newline.
+func parseFormat(flagName, format string) (*template.Template, error) {
+	if format == "" {
+		return nil, nil
+	}
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	tmpl, err := template.New(flagName).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", flagName, err)
+	}
+	return tmpl, nil
+}
+
+// printContainersFormatted prints each container through tmpl.
+func printContainersFormatted(out io.Writer, tmpl *template.Template, list []entities.ListContainer) error {
+	for _, c := range list {
+		ports := make([]string, 0, len(c.Ports))
+		for _, p := range c.Ports {
+			ports = append(ports, fmt.Sprintf("%d->%d/%s", p.HostPort, p.ContainerPort, p.Protocol))
+		}
+		row := containerRow{
+			ID:        shortID(c.ID),
+			Image:     c.Image,
+			Command:   strings.Join(c.Command, " "),
+			CreatedAt: time.Unix(c.Created, 0).String(),
+			Status:    c.State,
+			State:     c.State,
+			Names:     strings.Join(c.Names, ","),
+			Ports:     strings.Join(ports, ", "),
+			Labels:    formatLabels(c.Labels),
+			Pod:       shortID(c.Pod),
+		}
+		if err := tmpl.Execute(out, row); err != nil {
+			return fmt.Errorf("formatting container %s: %w", row.ID, err)
+		}
+	}
+	return nil
+}
+
+// printImagesFormatted prints each tag of each image through tmpl.  An
+// untagged image is printed once, as <none>:<none>.
+func printImagesFormatted(out io.Writer, tmpl *template.Template, list []*entities.ImageSummary) error {
+	for _, img := range list {
+		tags := img.RepoTags
+		if len(tags) == 0 {
+			tags = []string{"<none>:<none>"}
+		}
+		for _, ref := range tags {
+			repo, tag := splitReference(ref)
+			row := imageRow{
+				ID:           shortID(img.ID),
+				Repository:   repo,
+				Tag:          tag,
+				Digest:       img.Digest,
+				CreatedAt:    img.Created.String(),
+				CreatedSince: units.HumanDuration(time.Since(img.Created)) + " ago",
+				Size:         humanSize(img.Size),
+			}
+			if err := tmpl.Execute(out, row); err != nil {
+				return fmt.Errorf("formatting image %s: %w", row.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// shortID truncates an ID to the 12 characters podman prints.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
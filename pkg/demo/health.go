@@ -0,0 +1,135 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	"github.com/containers/libpod/v2/pkg/specgen"
+	"github.com/lsm5/bindings-sample/internal/retry"
+)
+
+// waitHealthy runs the container's healthcheck every interval until it
+// reports healthy, printing each result.  It gives up when the container
+// turns unhealthy or timeout elapses.
+//
+// The service normally runs healthchecks from systemd timers, which are not
+// always available, so we trigger each check ourselves.
+func waitHealthy(ctx, conn context.Context, id string, interval, timeout time.Duration, out io.Writer) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		results, err := containers.RunHealthCheck(conn, id)
+		if err != nil {
+			return fmt.Errorf("running healthcheck: %w", err)
+		}
+		fmt.Fprintf(out, "Healthcheck: %s", results.Status)
+		if n := len(results.Log); n > 0 {
+			last := results.Log[n-1]
+			fmt.Fprintf(out, " (exit code %d: %s)", last.ExitCode, strings.TrimSpace(last.Output))
+		}
+		fmt.Fprintln(out)
+
+		switch results.Status {
+		case "healthy":
+			return nil
+		case "unhealthy":
+			return fmt.Errorf("container is unhealthy after %d failed checks", results.FailingStreak)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return fmt.Errorf("container is not healthy after %s", timeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// portInterval is the time between attempts to connect to --wait-for-port.
+const portInterval = 250 * time.Millisecond
+
+// checkWaitForPort makes sure that the port of --wait-for-port, given as
+// HOST:PORT, is one the container publishes over TCP.
+func checkWaitForPort(addr string, ports []specgen.PortMapping) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --wait-for-port %q: expected HOST:PORT: %w", addr, err)
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid --wait-for-port %q: bad port: %w", addr, err)
+	}
+	for _, p := range ports {
+		if p.HostPort == uint16(n) && p.Protocol == "tcp" {
+			return nil
+		}
+	}
+	return fmt.Errorf("--wait-for-port %s: port %d is not published over TCP; add --publish %d:CTRPORT", addr, n, n)
+}
+
+// waitForPort connects to addr until it accepts a connection, and returns
+// how long that took.  It gives up when timeout elapses.
+//
+// A running container is not necessarily listening yet, and not every
+// image defines a healthcheck, so this works as a readiness probe.
+func waitForPort(ctx context.Context, addr string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	deadline, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := retry.Do(deadline, retry.Policy{Delay: portInterval}, func() error {
+		conn, err := net.DialTimeout("tcp", addr, portInterval)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	})
+	switch {
+	case err == nil:
+		return time.Since(start), nil
+	case ctx.Err() != nil:
+		return 0, ctx.Err()
+	}
+	return 0, fmt.Errorf("%s is not accepting connections after %s: %w", addr, timeout, err)
+}
+
+// waitStartup execs cmd in the container until it exits 0, at most retries
+// times with interval between attempts, printing the exit code of each
+// that fails.  It returns the number of attempts taken.
+//
+// Unlike --wait-for-port, this works for images that listen on no port,
+// and unlike a healthcheck it needs nothing set when the container is
+// created.  The command's output is discarded.
+func waitStartup(ctx, conn context.Context, id string, cmd []string, retries int, interval time.Duration, out io.Writer) (int, error) {
+	attempts := 0
+	err := retry.Do(ctx, retry.Policy{Attempts: retries, Delay: interval}, func() error {
+		attempts++
+		code, err := runExec(ctx, conn, id, cmd, nil, io.Discard, io.Discard)
+		if err != nil {
+			// The container is gone or the service refused; trying
+			// again will not help
+			return retry.Permanent(err)
+		}
+		if code != 0 {
+			fmt.Fprintf(out, "Startup probe %d/%d: exit code %d\n", attempts, retries, code)
+			return fmt.Errorf("exit code %d", code)
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		return attempts, nil
+	case ctx.Err() != nil:
+		return 0, ctx.Err()
+	}
+	return 0, fmt.Errorf("startup command %v did not succeed after %d attempts: %w", cmd, attempts, err)
+}
@@ -0,0 +1,25 @@
+package demo
+
+import (
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
+// humanSize formats a byte count with decimal units, as podman does, e.g.
+// "73.4MB".
+func humanSize(bytes int64) string {
+	return units.HumanSizeWithPrecision(float64(bytes), 3)
+}
+
+// humanDuration approximates a duration for use in a sentence, e.g. "about
+// a minute".  A negative duration, such as a start time slightly ahead of
+// our clock, is treated as zero.
+func humanDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	s := units.HumanDuration(d)
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,47 @@
+package demo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{0, "0B"},
+		{999, "999B"},
+		{1000, "1kB"},
+		{1234, "1.23kB"},
+		{73_400_000, "73.4MB"},
+		{5_000_000_000, "5GB"},
+	}
+	for _, tt := range tests {
+		if got := humanSize(tt.bytes); got != tt.want {
+			t.Errorf("humanSize(%d) = %q, want %q", tt.bytes, got, tt.want)
+		}
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "less than a second"},
+		{-5 * time.Second, "less than a second"},
+		{-time.Hour, "less than a second"},
+		{time.Second, "1 second"},
+		{45 * time.Second, "45 seconds"},
+		{90 * time.Second, "about a minute"},
+		{10 * time.Minute, "10 minutes"},
+		{time.Hour, "about an hour"},
+		{3 * 24 * time.Hour, "3 days"},
+	}
+	for _, tt := range tests {
+		if got := humanDuration(tt.d); got != tt.want {
+			t.Errorf("humanDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
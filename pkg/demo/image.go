@@ -0,0 +1,475 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/libpod/v2/pkg/bindings/images"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+	"github.com/docker/go-units"
+	"github.com/lsm5/bindings-sample/internal/retry"
+	"github.com/lsm5/bindings-sample/internal/sem"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// pullOptions builds the options for images.Pull from the command line.
+// Username and password are only sent when given, so an --authfile on
+// its own is used as-is.
+//
+// The pull options have no platform variant (such as arm/v7) in this
+// version of the bindings, so only the architecture and OS can be chosen.
+func pullOptions(opts Options) entities.ImagePullOptions {
+	return entities.ImagePullOptions{
+		Authfile:     opts.Authfile,
+		Username:     opts.Username,
+		Password:     opts.Password,
+		OverrideArch: opts.Arch,
+		OverrideOS:   opts.OS,
+	}
+}
+
+// pushOptions builds the options for images.Push, reusing the pull
+// credentials.
+func pushOptions(opts Options) entities.ImagePushOptions {
+	return entities.ImagePushOptions{
+		Authfile: opts.Authfile,
+		Username: opts.Username,
+		Password: opts.Password,
+	}
+}
+
+// splitReference splits an image reference into the repository and tag
+// that images.Tag expects.  The tag defaults to "latest".  A colon before
+// the last slash belongs to a registry port, not a tag.
+func splitReference(ref string) (repo, tag string) {
+	i := strings.LastIndex(ref, ":")
+	if i < 0 || i < strings.LastIndex(ref, "/") {
+		return ref, "latest"
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// digestLengths is the number of hex digits in a digest, by algorithm.
+var digestLengths = map[string]int{"sha256": 64, "sha384": 96, "sha512": 128}
+
+// splitDigest splits a reference pinned by digest, NAME[:TAG]@ALGO:HEX,
+// into NAME[:TAG] and ALGO:HEX, checking the digest's form so that a typo
+// fails before the pull rather than as a registry error.  A reference
+// without a digest comes back whole, with an empty digest.
+func splitDigest(ref string) (name, digest string, err error) {
+	i := strings.Index(ref, "@")
+	if i < 0 {
+		return ref, "", nil
+	}
+	name, digest = ref[:i], ref[i+1:]
+	if name == "" {
+		return "", "", fmt.Errorf("invalid image %q: no name before the digest", ref)
+	}
+	parts := strings.SplitN(digest, ":", 2)
+	n, ok := digestLengths[parts[0]]
+	if !ok || len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid image %q: the digest must be sha256:HEX, sha384:HEX or sha512:HEX", ref)
+	}
+	if len(parts[1]) != n || strings.Trim(parts[1], "0123456789abcdef") != "" {
+		return "", "", fmt.Errorf("invalid image %q: a %s digest is %d lowercase hex digits", ref, parts[0], n)
+	}
+	return name, digest, nil
+}
+
+// hasTag reports whether an image name, without a digest, gives a tag.
+func hasTag(name string) bool {
+	i := strings.LastIndex(name, ":")
+	return i >= 0 && i > strings.LastIndex(name, "/")
+}
+
+// pullReference returns the reference to pull ref by.  The service
+// refuses one with both a tag and a digest, so the tag is dropped: the
+// digest alone says what to pull, and checkPinnedDigest compares the tag
+// with it afterwards.
+func pullReference(ref string) string {
+	name, digest, err := splitDigest(ref)
+	if err != nil || digest == "" || !hasTag(name) {
+		return ref
+	}
+	repo, _ := splitReference(name)
+	return repo + "@" + digest
+}
+
+// checkPinnedDigest prints the digest of data, the image pulled for ref,
+// and warns if it is not the one ref is pinned to.  When ref gives a tag
+// as well, the image that tag names locally, if any, is compared too:
+// the digest is what was pulled, so a tag that has since moved on is only
+// worth a warning.
+func checkPinnedDigest(conn context.Context, ref string, data *entities.ImageInspectReport, logger *slog.Logger, out io.Writer) {
+	name, digest, _ := splitDigest(ref)
+	if digest == "" {
+		return
+	}
+	fmt.Fprintf(out, "Image is pinned to %s and resolved to %s\n", digest, data.Digest)
+	matched := string(data.Digest) == digest
+	for _, d := range data.RepoDigests {
+		// A digest pin may name a manifest list, not the image in it
+		matched = matched || strings.HasSuffix(d, "@"+digest)
+	}
+	if !matched {
+		logger.Warn(fmt.Sprintf("image %.12s has digest %s, not the pinned %s", data.ID, data.Digest, digest))
+	}
+	if !hasTag(name) {
+		return
+	}
+	logger.Debug("images.GetImage", "image", name)
+	tagged, err := images.GetImage(conn, name, nil)
+	switch {
+	case isNotFound(err):
+		logger.Debug("no local image to compare the tag with", "image", name)
+	case err != nil:
+		logger.Warn(fmt.Sprintf("could not inspect %s to compare it with the digest: %v", name, err))
+	case tagged.ID != data.ID:
+		logger.Warn(fmt.Sprintf("the tag and digest of %s disagree: %s is image %.12s, but the digest pulled %.12s", ref, name, tagged.ID, data.ID))
+	}
+}
+
+// needPull applies --pull-policy to an image: always pulls it, missing
+// pulls it only if it is not present yet, and never fails if it is not.
+func needPull(conn context.Context, image, policy string) (bool, error) {
+	if policy == "always" {
+		return true, nil
+	}
+	exists, err := images.Exists(conn, image)
+	if err != nil {
+		return false, fmt.Errorf("checking for image %s: %w", image, err)
+	}
+	return pullFor(image, exists, policy)
+}
+
+// pullFor is needPull for an image already known to exist or not.
+func pullFor(image string, exists bool, policy string) (bool, error) {
+	switch {
+	case policy == "always":
+		return true, nil
+	case !exists && policy == "never":
+		return false, failed(FailureImageNotFound, fmt.Errorf("image %s not found locally and --no-pull (or --pull-policy=never) set", image))
+	}
+	return !exists, nil
+}
+
+// PullImage pulls the image, retrying up to retries more times with a
+// doubling delay when the failure looks transient.  Progress is drawn on
+// progress, which may be io.Discard.
+func PullImage(ctx, conn context.Context, raw string, pullOpts entities.ImagePullOptions, retries int, backoff time.Duration, logger *slog.Logger, progress io.Writer) ([]string, error) {
+	policy := retry.Policy{
+		Attempts:   retries + 1,
+		Delay:      backoff,
+		Multiplier: 2,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			logger.Debug("retrying images.Pull", "image", raw, "attempt", attempt, "error", err, "delay", delay)
+		},
+	}
+	var ids []string
+	err := retry.Do(ctx, policy, func() error {
+		err := withProgress(ctx, progress, "Pulling "+raw, func() error {
+			var err error
+			ids, err = images.Pull(conn, raw, pullOpts)
+			return err
+		})
+		if err != nil && !isRetryablePullError(err) {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return ids, nil
+}
+
+// withPullTimeout runs fn, a pull or build, with its own timeout on top
+// of ctx, so that it can be given longer than the other steps' waits.  An
+// expired timeout is reported as such, naming the flag that sets it.
+// Zero means no timeout.
+func withPullTimeout(ctx context.Context, what string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	pullCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := fn(pullCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(pullCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s timed out after %s; raise --pull-timeout if it needs longer: %w", what, timeout, err)
+	}
+	return err
+}
+
+// pullResult is the outcome of pulling one of the --pull images.
+type pullResult struct {
+	image string
+	ids   []string
+	err   error
+}
+
+// pullImages pulls the images concurrently, at most parallelism at a time
+// and within what limit admits, and returns the outcome of each, in the
+// order given.  A failed pull does not stop the others unless failFast is
+// set; then the pulls in flight are abandoned and the rest are not
+// started.  The pulls draw no progress, as several bars at once would
+// garble each other.
+func pullImages(ctx, conn context.Context, refs []string, pullOpts entities.ImagePullOptions, parallelism int, limit *sem.Limiter, failFast bool, retries int, backoff, timeout time.Duration, logger *slog.Logger) []pullResult {
+	results := make([]pullResult, len(refs))
+	workers := semaphore.NewWeighted(int64(parallelism))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, ref := range refs {
+		results[i].image = ref
+		if err := workers.Acquire(gctx, 1); err != nil {
+			results[i].err = errors.New("not pulled after an earlier failure (--fail-fast)")
+			if ctx.Err() != nil {
+				results[i].err = ctx.Err()
+			}
+			continue
+		}
+		i, ref := i, ref
+		g.Go(func() error {
+			defer workers.Release(1)
+			logger.Debug("images.Pull", "image", ref)
+			var ids []string
+			err := limit.Do(gctx, func() error {
+				return withPullTimeout(gctx, "pulling "+ref, timeout, func(ctx context.Context) error {
+					var err error
+					ids, err = PullImage(ctx, conn, ref, pullOpts, retries, backoff, logger, io.Discard)
+					return err
+				})
+			})
+			results[i].ids, results[i].err = ids, err
+			if failFast {
+				return err
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	return results
+}
+
+// printPullResults prints which of the --pull images were pulled, with
+// the ID of each, and which failed, with the error.
+func printPullResults(out io.Writer, results []pullResult) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE\tRESULT")
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			fmt.Fprintf(w, "%s\tfailed: %v\n", r.image, r.err)
+		case len(r.ids) > 0:
+			fmt.Fprintf(w, "%s\tpulled %.12s\n", r.image, r.ids[0])
+		default:
+			fmt.Fprintf(w, "%s\tpulled\n", r.image)
+		}
+	}
+	w.Flush()
+}
+
+// allTagsReference checks the --image of an --all-tags pull, which names
+// a whole repository, and returns the reference of the tag to run once it
+// is pulled.
+func allTagsReference(repo, tag string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(repo)
+	if err != nil {
+		return "", fmt.Errorf("invalid --image %q: %w", repo, err)
+	}
+	if !reference.IsNameOnly(named) {
+		return "", fmt.Errorf("invalid --image %q: --all-tags pulls a whole repository, so it takes one without a tag or digest", repo)
+	}
+	tagged, err := reference.WithTag(named, tag)
+	if err != nil {
+		return "", fmt.Errorf("invalid --all-tags-run %q: %w", tag, err)
+	}
+	return tagged.String(), nil
+}
+
+// pulledTags returns a result for each tag of repo among the images an
+// --all-tags pull fetched, sorted by reference.  images.Pull only returns
+// the IDs of the images, several of which a tag may share.
+func pulledTags(conn context.Context, repo string, ids []string) ([]pullResult, error) {
+	named, err := reference.ParseNormalizedNamed(repo)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var tags []pullResult
+	for _, id := range ids {
+		data, err := images.GetImage(conn, id, nil)
+		if err != nil {
+			return nil, fmt.Errorf("inspecting pulled image %.12s: %w", id, err)
+		}
+		for _, t := range data.RepoTags {
+			n, err := reference.ParseNormalizedNamed(t)
+			if err != nil || n.Name() != named.Name() || seen[n.String()] {
+				continue
+			}
+			seen[n.String()] = true
+			tags = append(tags, pullResult{image: n.String(), ids: []string{data.ID}})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].image < tags[j].image })
+	return tags, nil
+}
+
+// isRetryablePullError reports whether a failed pull is worth retrying:
+// network errors and server-side errors are, unless the registry turned
+// down the credentials or does not know the image.  The service reports
+// registry failures as 500s, so the message has to be checked as well.
+func isRetryablePullError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, fatal := range []string{"unauthorized", "authentication required", "denied", "manifest unknown", "not found"} {
+		if strings.Contains(msg, fatal) {
+			return false
+		}
+	}
+
+	var apiErr entities.ErrorModel
+	if errors.As(err, &apiErr) {
+		return apiErr.Code() >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isImageNotFound reports whether a failed pull means that the registry
+// does not have the image.  Like isRetryablePullError, it has to go by the
+// message.
+func isImageNotFound(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, missing := range []string{"manifest unknown", "name unknown", "not found"} {
+		if strings.Contains(msg, missing) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskPassword hides a password so it can be logged safely.
+func maskPassword(password string) string {
+	if password == "" {
+		return ""
+	}
+	return "********"
+}
+
+// removeImage removes the image and prints what was untagged and deleted.
+// An image that is already gone is not an error.
+func removeImage(conn context.Context, name string, out io.Writer) error {
+	report, err := images.Remove(conn, name, false)
+	if err != nil {
+		var apiErr entities.ErrorModel
+		switch {
+		case isNotFound(err):
+			fmt.Fprintf(out, "Image %s is already gone\n", name)
+			return nil
+		case errors.As(err, &apiErr) && apiErr.Code() == http.StatusConflict:
+			return fmt.Errorf("image %s is still used by a container (run with --rm to remove it first): %w", name, err)
+		}
+		return fmt.Errorf("removing image %s: %w", name, err)
+	}
+	for _, untagged := range report.Untagged {
+		fmt.Fprintf(out, "Untagged: %s\n", untagged)
+	}
+	for _, deleted := range report.Deleted {
+		fmt.Fprintf(out, "Deleted: %s\n", deleted)
+	}
+	return nil
+}
+
+// checkSave verifies that the directory an archive would be written to
+// exists.
+func checkSave(flagName, path string) error {
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", flagName, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid %s: %s is not a directory", flagName, filepath.Dir(path))
+	}
+	return nil
+}
+
+// checkLoad verifies that the archive to load is a regular file.
+func checkLoad(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("invalid --load: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("invalid --load: %s is not a regular file", path)
+	}
+	return nil
+}
+
+// saveImage writes the image to path as an archive.  The archive is
+// streamed straight from the service to the file.
+func saveImage(conn context.Context, name, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	err = images.Export(conn, name, f, nil, nil)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("saving image %s to %s: %w", name, path, err)
+	}
+	return nil
+}
+
+// loadImage feeds the archive at path to the service and returns the names
+// of the images it contained.  The file is streamed as the request body.
+func loadImage(conn context.Context, path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report, err := images.Load(conn, f, nil)
+	if err != nil {
+		return nil, fmt.Errorf("loading images from %s: %w", path, err)
+	}
+	return report.Names, nil
+}
+
+// printHistory prints the image's layers, newest first, like `podman
+// history`.  Commands longer than width runes are cut short; a width of 0
+// prints them in full.
+func printHistory(conn context.Context, image string, width int, out io.Writer) error {
+	layers, err := images.History(conn, image)
+	if err != nil {
+		return fmt.Errorf("reading the history of image %s: %w", image, err)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tCREATED BY\tSIZE")
+	for _, l := range layers {
+		createdBy := strings.Join(strings.Fields(l.CreatedBy), " ")
+		if r := []rune(createdBy); width > 0 && len(r) > width {
+			createdBy = string(r[:width-1]) + "…"
+		}
+		fmt.Fprintf(w, "%s\t%s ago\t%s\t%s\n", shortID(l.ID), units.HumanDuration(time.Since(time.Unix(l.Created, 0))),
+			createdBy, humanSize(l.Size))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,76 @@
+package demo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitDigest(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	tests := []struct {
+		ref, name, digest string
+		wantErr           bool
+	}{
+		{ref: "fedora:33", name: "fedora:33"},
+		{ref: "registry.example.com:5000/app", name: "registry.example.com:5000/app"},
+		{ref: "fedora@sha256:" + sum, name: "fedora", digest: "sha256:" + sum},
+		{ref: "fedora:33@sha256:" + sum, name: "fedora:33", digest: "sha256:" + sum},
+		{ref: "@sha256:" + sum, wantErr: true},
+		{ref: "fedora@" + sum, wantErr: true},
+		{ref: "fedora@md5:" + sum, wantErr: true},
+		{ref: "fedora@sha256:" + sum[1:], wantErr: true},
+		{ref: "fedora@sha256:" + strings.ToUpper(sum), wantErr: true},
+		{ref: "fedora@sha512:" + sum, wantErr: true},
+	}
+	for _, tt := range tests {
+		name, digest, err := splitDigest(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitDigest(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if name != tt.name || digest != tt.digest {
+			t.Errorf("splitDigest(%q) = %q, %q; want %q, %q", tt.ref, name, digest, tt.name, tt.digest)
+		}
+	}
+}
+
+func TestPullReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("0", 64)
+	tests := []struct{ ref, want string }{
+		{"fedora:33", "fedora:33"},
+		{"fedora@" + digest, "fedora@" + digest},
+		{"fedora:33@" + digest, "fedora@" + digest},
+		{"localhost:5000/app:v1@" + digest, "localhost:5000/app@" + digest},
+		{"localhost:5000/app@" + digest, "localhost:5000/app@" + digest},
+	}
+	for _, tt := range tests {
+		if got := pullReference(tt.ref); got != tt.want {
+			t.Errorf("pullReference(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestAllTagsReference(t *testing.T) {
+	tests := []struct {
+		repo, tag, want string
+		wantErr         bool
+	}{
+		{repo: "alpine", tag: "latest", want: "docker.io/library/alpine:latest"},
+		{repo: "quay.io/podman/hello", tag: "v1.0", want: "quay.io/podman/hello:v1.0"},
+		{repo: "localhost:5000/app", tag: "3", want: "localhost:5000/app:3"},
+		{repo: "alpine:3.12", tag: "latest", wantErr: true},
+		{repo: "alpine@sha256:" + strings.Repeat("0", 64), tag: "latest", wantErr: true},
+		{repo: "alpine", tag: "no/slashes", wantErr: true},
+		{repo: "Alpine", tag: "latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := allTagsReference(tt.repo, tt.tag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("allTagsReference(%q, %q) error = %v, wantErr %v", tt.repo, tt.tag, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("allTagsReference(%q, %q) = %q, want %q", tt.repo, tt.tag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containers/libpod/v2/libpod/define"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+)
+
+// printContainer prints the interesting parts of the container's inspect
+// data.  Settings that were not requested on the command line are only
+// shown when they differ from the defaults.
+func printContainer(out io.Writer, data *define.InspectContainerData, opts Options) {
+	if data.Rootfs != "" {
+		fmt.Fprintf(out, "Container runs from the root filesystem in %s\n", data.Rootfs)
+	} else {
+		fmt.Fprintf(out, "Container uses image %s (requested %s)\n", data.ImageName, opts.Image)
+	}
+	fmt.Fprintf(out, "Container running status is %s\n", data.State.Status)
+	if data.State.Running && !data.State.StartedAt.IsZero() {
+		fmt.Fprintf(out, "Container has been up for %s\n", humanDuration(time.Since(data.State.StartedAt)))
+	}
+	if c := data.Config; c != nil {
+		if opts.Hostname != "" {
+			fmt.Fprintf(out, "Container hostname is %s\n", c.Hostname)
+		}
+		if c.User != "" {
+			fmt.Fprintf(out, "Container runs as user %s\n", c.User)
+		}
+		if c.WorkingDir != "" {
+			fmt.Fprintf(out, "Container working directory is %s\n", c.WorkingDir)
+		}
+		if opts.NoEntrypoint || opts.Entrypoint != "" {
+			if c.Entrypoint == "" {
+				fmt.Fprintf(out, "Container has no entrypoint; its command is %q\n", c.Cmd)
+			} else {
+				fmt.Fprintf(out, "Container entrypoint is %s\n", c.Entrypoint)
+			}
+		}
+		if opts.Umask != "" {
+			fmt.Fprintf(out, "Container process umask is %s, set by its entrypoint %s\n", opts.Umask, c.Entrypoint)
+		}
+		if opts.TZ != "" {
+			fmt.Fprintf(out, "Container timezone is %s\n", opts.TZ)
+		}
+		if opts.StopSignal != 0 {
+			fmt.Fprintf(out, "Container stop signal is %d (%s)\n", c.StopSignal, syscall.Signal(c.StopSignal))
+		}
+		// The service adds annotations of its own, so only echo ours
+		for _, a := range opts.Annotations {
+			key := strings.SplitN(a, "=", 2)[0]
+			fmt.Fprintf(out, "Container annotation %s=%s\n", key, c.Annotations[key])
+		}
+	}
+
+	if data.NetworkSettings != nil {
+		ports := make([]string, 0, len(data.NetworkSettings.Ports))
+		for port := range data.NetworkSettings.Ports {
+			ports = append(ports, port)
+		}
+		sort.Strings(ports)
+		for _, port := range ports {
+			for _, binding := range data.NetworkSettings.Ports[port] {
+				fmt.Fprintf(out, "Container port %s is published on %s:%s\n", port, binding.HostIP, binding.HostPort)
+			}
+		}
+
+		ip := data.NetworkSettings.IPAddress
+		if n, ok := data.NetworkSettings.Networks[opts.Network]; ok {
+			ip = n.IPAddress
+		}
+		if ip != "" {
+			fmt.Fprintf(out, "Container IP address is %s\n", ip)
+		}
+	}
+
+	if len(opts.CapAdd) > 0 || len(opts.CapDrop) > 0 {
+		fmt.Fprintf(out, "Container effective capabilities are %s\n", strings.Join(data.EffectiveCaps, ","))
+	}
+
+	if hc := data.HostConfig; hc != nil {
+		paths := make([]string, 0, len(hc.Tmpfs))
+		for path := range hc.Tmpfs {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fmt.Fprintf(out, "Container has a tmpfs at %s (%s)\n", path, hc.Tmpfs[path])
+		}
+
+		if len(hc.ExtraHosts) > 0 {
+			fmt.Fprintf(out, "Container /etc/hosts has extra entries %s\n", strings.Join(hc.ExtraHosts, ", "))
+		}
+		if len(hc.Dns) > 0 {
+			fmt.Fprintf(out, "Container DNS servers are %s\n", strings.Join(hc.Dns, ", "))
+		}
+		if len(hc.DnsSearch) > 0 {
+			fmt.Fprintf(out, "Container DNS search domains are %s\n", strings.Join(hc.DnsSearch, ", "))
+		}
+		for _, ns := range []struct{ name, flag, mode string }{
+			{"cgroup", opts.CgroupNS, hc.CgroupMode},
+			{"PID", opts.PidNS, hc.PidMode},
+			{"IPC", opts.IpcNS, hc.IpcMode},
+			{"UTS", opts.UtsNS, hc.UTSMode},
+		} {
+			if ns.flag != "" {
+				fmt.Fprintf(out, "Container %s namespace is %s\n", ns.name, ns.mode)
+			}
+		}
+		if opts.UserNS != "" || len(opts.UIDMaps) > 0 || len(opts.GIDMaps) > 0 {
+			mode := hc.UsernsMode
+			if mode == "" {
+				mode = "the host's"
+			}
+			fmt.Fprintf(out, "Container user namespace is %s\n", mode)
+		}
+		if len(opts.GroupAdd) > 0 {
+			fmt.Fprintf(out, "Container user is also in groups %s\n", strings.Join(hc.GroupAdd, ", "))
+		}
+		if hc.Privileged {
+			fmt.Fprintln(out, "Container is privileged")
+		}
+		if hc.ReadonlyRootfs {
+			fmt.Fprintln(out, "Container root filesystem is read-only")
+		}
+		if hc.Init {
+			fmt.Fprintln(out, "Container runs an init process as PID 1")
+		}
+		if hc.Memory > 0 {
+			fmt.Fprintf(out, "Container memory limit is %d bytes\n", hc.Memory)
+		}
+		if opts.ShmSize != "" {
+			fmt.Fprintf(out, "Container /dev/shm size is %d bytes\n", hc.ShmSize)
+		}
+		if opts.OOMScoreAdj != 0 {
+			fmt.Fprintf(out, "Container OOM score adjustment is %d\n", hc.OomScoreAdj)
+		}
+		if hc.CpuQuota > 0 && hc.CpuPeriod > 0 {
+			fmt.Fprintf(out, "Container CPU limit is %.2f CPUs (quota %dus per %dus period)\n",
+				float64(hc.CpuQuota)/float64(hc.CpuPeriod), hc.CpuQuota, hc.CpuPeriod)
+		}
+	}
+
+	if hc := data.HostConfig; hc != nil && hc.RestartPolicy != nil && hc.RestartPolicy.Name != "" {
+		fmt.Fprintf(out, "Container restart policy is %s", hc.RestartPolicy.Name)
+		if hc.RestartPolicy.MaximumRetryCount > 0 {
+			fmt.Fprintf(out, " (up to %d retries)", hc.RestartPolicy.MaximumRetryCount)
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+// printImage prints the image's inspect data.  verbose adds the digest,
+// creation time, entrypoint, command and labels.
+func printImage(out io.Writer, data *entities.ImageInspectReport, verbose bool) {
+	fmt.Fprintf(out, "Image ID is %.12s\n", data.ID)
+	fmt.Fprintf(out, "Image platform is %s/%s\n", data.Os, data.Architecture)
+	fmt.Fprintf(out, "Image size is %s\n", humanSize(data.Size))
+	if !verbose {
+		return
+	}
+
+	fmt.Fprintf(out, "Image digest is %s\n", data.Digest)
+	if data.Created != nil {
+		fmt.Fprintf(out, "Image was created %s ago, at %s\n", humanDuration(time.Since(*data.Created)), data.Created.Format(time.RFC3339))
+	}
+	if data.Config != nil {
+		fmt.Fprintf(out, "Image entrypoint is %q\n", data.Config.Entrypoint)
+		fmt.Fprintf(out, "Image command is %q\n", data.Config.Cmd)
+	}
+	keys := make([]string, 0, len(data.Labels))
+	for k := range data.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(out, "Image label %s=%s\n", k, data.Labels[k])
+	}
+}
+
+// printImageSizes prints the ID and size of each image, and their total.
+// Images that share layers count them more than once, so the total can be
+// more than removing them all would free.
+func printImageSizes(out io.Writer, list []*entities.ImageSummary) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE ID\tSIZE")
+	var total int64
+	for _, img := range list {
+		fmt.Fprintf(w, "%s\t%s\n", shortID(img.ID), humanSize(img.Size))
+		total += img.Size
+	}
+	fmt.Fprintf(w, "%d images\t%s\n", len(list), humanSize(total))
+	w.Flush()
+}
+
+// printContainerTable prints one line per container, like `podman ps`.
+func printContainerTable(out io.Writer, list []entities.ListContainer) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER ID\tNAMES\tIMAGE\tSTATUS\tLABELS")
+	for _, c := range list {
+		fmt.Fprintf(w, "%.12s\t%s\t%s\t%s\t%s\n", c.ID, strings.Join(c.Names, ","), c.Image, c.State, formatLabels(c.Labels))
+	}
+	w.Flush()
+}
+
+// formatLabels joins labels into a stable KEY=VALUE,... string.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// checkInspectOut verifies, before anything is created, that --inspect-out
+// will not overwrite a file unless force is set.
+func checkInspectOut(path string, force bool) error {
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return fmt.Errorf("invalid --inspect-out: %w", err)
+	case info.IsDir():
+		return fmt.Errorf("invalid --inspect-out: %s is a directory", path)
+	case !force:
+		return fmt.Errorf("--inspect-out %s already exists; remove it or pass --force", path)
+	}
+	return nil
+}
+
+// writeInspect writes the container's full inspect data to path as
+// indented JSON, creating the directories it is in.  The file is synced
+// before it is closed, so it is complete once the run is over.
+func writeInspect(path string, data *define.InspectContainerData, force bool) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling the inspect data: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("writing --inspect-out: %w", err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("writing --inspect-out: %w", err)
+	}
+	_, err = f.Write(append(content, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("writing --inspect-out %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package demo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/libpod/v2/libpod/define"
+)
+
+func TestWriteInspect(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out", "inspect.json")
+	if err := checkInspectOut(path, false); err != nil {
+		t.Fatalf("checkInspectOut() of a new file = %v", err)
+	}
+	if err := writeInspect(path, &define.InspectContainerData{ID: "abc", Name: "web"}, false); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got define.InspectContainerData
+	if err := json.Unmarshal(content, &got); err != nil {
+		t.Fatalf("the file is not inspect JSON: %v", err)
+	}
+	if got.ID != "abc" || got.Name != "web" {
+		t.Errorf("wrote ID %q and name %q, want abc and web", got.ID, got.Name)
+	}
+
+	if err := checkInspectOut(path, false); err == nil {
+		t.Error("checkInspectOut() of an existing file succeeded without force")
+	}
+	if err := writeInspect(path, &define.InspectContainerData{ID: "def"}, false); err == nil {
+		t.Error("writeInspect() overwrote an existing file without force")
+	}
+	if err := writeInspect(path, &define.InspectContainerData{ID: "def"}, true); err != nil {
+		t.Errorf("writeInspect() with force = %v", err)
+	}
+	if err := checkInspectOut(dir, true); err == nil {
+		t.Error("checkInspectOut() of a directory succeeded")
+	}
+}
@@ -0,0 +1,186 @@
+package demo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"time"
+
+	"github.com/containers/libpod/v2/pkg/bindings/generate"
+	"github.com/containers/libpod/v2/pkg/bindings/play"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+	"gopkg.in/yaml.v2"
+)
+
+// generateKube writes Kubernetes YAML describing the container or pod
+// nameOrID to path.  The YAML is checked to parse before anything is
+// written, so a bad response does not leave a broken file behind.
+func generateKube(conn context.Context, nameOrID, path string) error {
+	report, err := generate.Kube(conn, nameOrID, entities.GenerateKubeOptions{})
+	if err != nil {
+		return fmt.Errorf("generating Kubernetes YAML: %w", err)
+	}
+	if c, ok := report.Reader.(io.Closer); ok {
+		defer c.Close()
+	}
+	data, err := ioutil.ReadAll(report.Reader)
+	if err != nil {
+		return fmt.Errorf("reading Kubernetes YAML: %w", err)
+	}
+
+	// The YAML may hold several documents, such as a pod and a service
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("generated Kubernetes YAML does not parse: %w", err)
+		}
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// kubeObject is the part of a Kubernetes Pod or Deployment that play kube
+// needs from us: the names it will give the pods and the images it runs.
+type kubeObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Replicas   *int32          `yaml:"replicas"`
+		Containers []kubeContainer `yaml:"containers"`
+		Template   struct {
+			Spec struct {
+				Containers []kubeContainer `yaml:"containers"`
+			} `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+type kubeContainer struct {
+	Image string `yaml:"image"`
+}
+
+// kubeManifest lists the pods a manifest deploys and the images they use.
+type kubeManifest struct {
+	Pods   []string
+	Images []string
+}
+
+// readKube reads the Kubernetes YAML at path.  Pod names follow play
+// kube: a Pod keeps its own name and the replicas of a Deployment are
+// named NAME-pod-0, NAME-pod-1 and so on.
+func readKube(path string) (*kubeManifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &kubeManifest{}
+	seen := map[string]bool{}
+	addImages := func(ctrs []kubeContainer) {
+		for _, c := range ctrs {
+			if c.Image != "" && !seen[c.Image] {
+				seen[c.Image] = true
+				m.Images = append(m.Images, c.Image)
+			}
+		}
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var obj kubeObject
+		err := dec.Decode(&obj)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		switch obj.Kind {
+		case "Pod":
+			m.Pods = append(m.Pods, obj.Metadata.Name)
+			addImages(obj.Spec.Containers)
+		case "Deployment":
+			replicas := int32(1)
+			if obj.Spec.Replicas != nil {
+				replicas = *obj.Spec.Replicas
+			}
+			for i := int32(0); i < replicas; i++ {
+				m.Pods = append(m.Pods, fmt.Sprintf("%s-pod-%d", obj.Metadata.Name, i))
+			}
+			addImages(obj.Spec.Template.Spec.Containers)
+		case "":
+			// An empty document, such as a trailing "---"
+		default:
+			return nil, fmt.Errorf("%s: play kube only supports Pod and Deployment, not %s", path, obj.Kind)
+		}
+	}
+	if len(m.Pods) == 0 {
+		return nil, fmt.Errorf("%s: no Pod or Deployment found", path)
+	}
+	return m, nil
+}
+
+// pullKubeImages pulls the images the manifest uses, as --pull-policy
+// says.  play kube would pull missing ones itself, but in a single request
+// with no progress and no retry, so a slow registry looks like a hang.
+func pullKubeImages(ctx, conn context.Context, m *kubeManifest, policy string, pullOpts entities.ImagePullOptions, retries int, backoff time.Duration, logger *slog.Logger, progress io.Writer) error {
+	for _, image := range m.Images {
+		pull, err := needPull(conn, image, policy)
+		if err != nil {
+			return err
+		}
+		if !pull {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Pulling image %s...", image))
+		if _, err := PullImage(ctx, conn, image, pullOpts, retries, backoff, logger, progress); err != nil {
+			return fmt.Errorf("pulling image %s: %w", image, err)
+		}
+	}
+	return nil
+}
+
+// playKube deploys the manifest at path and prints the pods and
+// containers it created.
+func playKube(conn context.Context, path string, opts Options, out io.Writer) error {
+	report, err := play.Kube(conn, path, entities.PlayKubeOptions{
+		Authfile: opts.Authfile,
+		Username: opts.Username,
+		Password: opts.Password,
+		Network:  opts.Network,
+	})
+	if err != nil {
+		return fmt.Errorf("playing %s: %w", path, err)
+	}
+	for _, p := range report.Pods {
+		fmt.Fprintf(out, "Pod %.12s with %d containers:\n", p.ID, len(p.Containers))
+		for _, id := range p.Containers {
+			fmt.Fprintf(out, "  %.12s\n", id)
+		}
+		for _, l := range p.Logs {
+			fmt.Fprintf(out, "  %s\n", l)
+		}
+	}
+	return nil
+}
+
+// downKube removes the pods the manifest deployed.  This version of the
+// bindings has no play kube --down, so the pods are found by name.
+func downKube(conn context.Context, m *kubeManifest, out io.Writer) error {
+	for _, name := range m.Pods {
+		fmt.Fprintf(out, "Removing pod %s...\n", name)
+		if err := removePod(conn, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
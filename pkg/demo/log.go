@@ -0,0 +1,115 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// narrativeHandler is a slog.Handler that prints messages the way the
+// tutorial always has: the bare message on its own line, followed by any
+// attributes as key=value pairs.  Warnings and errors go to stderr with a
+// prefix, so they survive --quiet and --output=json, as does the debug
+// output.
+type narrativeHandler struct {
+	out   io.Writer
+	level slog.Level
+	attrs []slog.Attr
+}
+
+func newLogger(out io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(&narrativeHandler{out: out, level: level})
+}
+
+func (h *narrativeHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *narrativeHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	w := h.out
+	switch {
+	case r.Level >= slog.LevelError:
+		w = os.Stderr
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		w = os.Stderr
+		b.WriteString("Warning: ")
+	case r.Level < slog.LevelInfo:
+		w = os.Stderr
+		b.WriteString("Debug: ")
+	}
+	b.WriteString(r.Message)
+
+	writeAttr := func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(writeAttr)
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (h *narrativeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &h2
+}
+
+// WithGroup is not needed by the tutorial, so groups are flattened.
+func (h *narrativeHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// stepRecorder wraps a handler and remembers the last step announced at
+// info level, such as "Pulling image...", even when the narrative itself
+// is not printed.  Run uses it to say where a --deadline hit.
+type stepRecorder struct {
+	slog.Handler
+	mu   *sync.Mutex
+	step *string
+}
+
+func newStepRecorder(h slog.Handler) *stepRecorder {
+	return &stepRecorder{Handler: h, mu: new(sync.Mutex), step: new(string)}
+}
+
+func (h *stepRecorder) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *stepRecorder) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level == slog.LevelInfo && strings.HasSuffix(r.Message, "...") {
+		h.mu.Lock()
+		*h.step = strings.TrimSuffix(r.Message, "...")
+		h.mu.Unlock()
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *stepRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &stepRecorder{Handler: h.Handler.WithAttrs(attrs), mu: h.mu, step: h.step}
+}
+
+func (h *stepRecorder) WithGroup(name string) slog.Handler {
+	return &stepRecorder{Handler: h.Handler.WithGroup(name), mu: h.mu, step: h.step}
+}
+
+// last returns the step most recently announced.
+func (h *stepRecorder) last() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return *h.step
+}
@@ -0,0 +1,55 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/containers/libpod/v2/libpod/image"
+	"github.com/containers/libpod/v2/pkg/bindings/images"
+	"github.com/containers/libpod/v2/pkg/bindings/manifests"
+)
+
+// createManifest creates an empty manifest list, adds the image to it and
+// prints the entries of the resulting list.
+func createManifest(conn context.Context, name, img string, out io.Writer) error {
+	if _, err := manifests.Create(conn, []string{name}, nil, nil); err != nil {
+		return fmt.Errorf("creating manifest list %s: %w", name, err)
+	}
+	if _, err := manifests.Add(conn, name, image.ManifestAddOpts{Images: []string{img}}); err != nil {
+		return fmt.Errorf("adding %s to manifest list %s: %w", img, name, err)
+	}
+
+	list, err := manifests.Inspect(conn, name)
+	if err != nil {
+		return fmt.Errorf("inspecting manifest list %s: %w", name, err)
+	}
+	fmt.Fprintf(out, "Manifest list %s has %d entries\n", name, len(list.Manifests))
+	for _, m := range list.Manifests {
+		fmt.Fprintf(out, "  %s %s/%s (%d bytes)\n", m.Digest, m.Platform.OS, m.Platform.Architecture, m.Size)
+	}
+	return nil
+}
+
+// pushManifest pushes the manifest list and the images it refers to.
+//
+// The manifest push endpoint takes no credentials in this version, so the
+// --username, --password and --authfile flags cannot be passed on; the
+// service's own registry login is used instead.
+func pushManifest(conn context.Context, name string) error {
+	dest := "docker://" + name
+	all := true
+	if _, err := manifests.Push(conn, name, &dest, &all); err != nil {
+		return fmt.Errorf("pushing manifest list %s: %w", name, err)
+	}
+	return nil
+}
+
+// removeManifest removes the manifest list, which is stored as an image.
+// A list that is already gone is not an error.
+func removeManifest(conn context.Context, name string) error {
+	if _, err := images.Remove(conn, name, false); err != nil && !isNotFound(err) {
+		return fmt.Errorf("removing manifest list %s: %w", name, err)
+	}
+	return nil
+}
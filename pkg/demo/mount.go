@@ -0,0 +1,201 @@
+package demo
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/containers/libpod/v2/pkg/specgen"
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// imageVolume mounts an image's filesystem in the container, as
+// --mount-type type=image does.  This version of the SpecGenerator has no
+// field for it, so createWithSpec adds it to the spec in the form of the
+// image_volumes field of Podman 3.0 and later; older services ignore it.
+type imageVolume struct {
+	Source      string
+	Destination string
+	ReadWrite   bool
+}
+
+// mountKeys are the keys each --mount-type type accepts, besides type and
+// target.
+var mountKeys = map[string][]string{
+	"bind":   {"source", "readonly", "bind-propagation", "bind-nonrecursive", "relabel"},
+	"volume": {"source", "readonly", "volume-nocopy"},
+	"tmpfs":  {"readonly", "tmpfs-size", "tmpfs-mode"},
+	"image":  {"source", "rw"},
+}
+
+// mountKeyAliases are the other spellings Docker and Podman accept.
+var mountKeyAliases = map[string]string{
+	"src":         "source",
+	"dst":         "target",
+	"destination": "target",
+	"ro":          "readonly",
+	"readwrite":   "rw",
+}
+
+// mountBoolKeys may be given without a value, meaning true.
+var mountBoolKeys = map[string]bool{
+	"readonly":          true,
+	"bind-nonrecursive": true,
+	"volume-nocopy":     true,
+	"rw":                true,
+}
+
+// parseMountTypes converts --mount-type entries, comma-separated KEY=VALUE
+// lists such as type=bind,source=/srv,target=/data,readonly, into bind and
+// tmpfs mounts, named volumes and image volumes.  Every mount needs a type
+// and a target; the other keys depend on the type and are listed in
+// mountKeys.  A volume without a source is an anonymous one.
+func parseMountTypes(entries []string) ([]spec.Mount, []*specgen.NamedVolume, []imageVolume, error) {
+	var (
+		mounts  []spec.Mount
+		volumes []*specgen.NamedVolume
+		images  []imageVolume
+	)
+	for _, e := range entries {
+		fields, err := parseMountFields(e)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		invalid := func(format string, args ...interface{}) error {
+			return fmt.Errorf("invalid --mount-type %q: %s", e, fmt.Sprintf(format, args...))
+		}
+		typ, target := fields["type"], fields["target"]
+		allowed, ok := mountKeys[typ]
+		switch {
+		case typ == "":
+			return nil, nil, nil, invalid("type is required: bind, volume, tmpfs or image")
+		case !ok:
+			return nil, nil, nil, invalid("unknown type %q: must be bind, volume, tmpfs or image", typ)
+		case !filepath.IsAbs(target):
+			return nil, nil, nil, invalid("target must be an absolute container path")
+		}
+		for key := range fields {
+			if key != "type" && key != "target" && !hasOption(allowed, key) {
+				return nil, nil, nil, invalid("unknown key %q for type=%s; it takes %s", key, typ, strings.Join(allowed, ", "))
+			}
+		}
+		bools := make(map[string]bool)
+		for key := range mountBoolKeys {
+			if value, ok := fields[key]; ok {
+				if bools[key], err = strconv.ParseBool(value); err != nil {
+					return nil, nil, nil, invalid("%s must be true or false", key)
+				}
+			}
+		}
+		source := fields["source"]
+
+		switch typ {
+		case "bind":
+			if !filepath.IsAbs(source) {
+				return nil, nil, nil, invalid("source must be an absolute host path")
+			}
+			var mountOpts []string
+			if bools["readonly"] {
+				mountOpts = append(mountOpts, "ro")
+			}
+			if p, ok := fields["bind-propagation"]; ok {
+				switch p {
+				case "shared", "slave", "private", "rshared", "rslave", "rprivate":
+					mountOpts = append(mountOpts, p)
+				default:
+					return nil, nil, nil, invalid("bind-propagation must be shared, slave, private, rshared, rslave or rprivate")
+				}
+			}
+			switch fields["relabel"] {
+			case "":
+			case "shared":
+				mountOpts = append(mountOpts, "z")
+			case "private":
+				mountOpts = append(mountOpts, "Z")
+			default:
+				return nil, nil, nil, invalid("relabel must be shared or private")
+			}
+			if bools["bind-nonrecursive"] {
+				mountOpts = append(mountOpts, "bind")
+			} else {
+				mountOpts = append(mountOpts, "rbind")
+			}
+			mounts = append(mounts, spec.Mount{Type: "bind", Source: source, Destination: target, Options: mountOpts})
+		case "volume":
+			if strings.ContainsRune(source, '/') {
+				return nil, nil, nil, invalid("source must be a volume name; use type=bind for a host path")
+			}
+			var mountOpts []string
+			if bools["readonly"] {
+				mountOpts = append(mountOpts, "ro")
+			}
+			if bools["volume-nocopy"] {
+				mountOpts = append(mountOpts, "nocopy")
+			}
+			volumes = append(volumes, &specgen.NamedVolume{Name: source, Dest: target, Options: mountOpts})
+		case "tmpfs":
+			// Checked here so that parseTmpfs, which applies the same
+			// defaults as --tmpfs, cannot fail
+			var tmpfsOpts []string
+			if bools["readonly"] {
+				tmpfsOpts = append(tmpfsOpts, "ro")
+			}
+			if size, ok := fields["tmpfs-size"]; ok {
+				if _, err := parseSize(size); err != nil {
+					return nil, nil, nil, invalid("tmpfs-size: %v", err)
+				}
+				tmpfsOpts = append(tmpfsOpts, "size="+size)
+			}
+			if mode, ok := fields["tmpfs-mode"]; ok {
+				if _, err := strconv.ParseUint(mode, 8, 32); err != nil {
+					return nil, nil, nil, invalid("tmpfs-mode must be octal, such as 1777")
+				}
+				tmpfsOpts = append(tmpfsOpts, "mode="+mode)
+			}
+			entry := target
+			if len(tmpfsOpts) > 0 {
+				entry += ":" + strings.Join(tmpfsOpts, ",")
+			}
+			tmpfs, err := parseTmpfs([]string{entry})
+			if err != nil {
+				return nil, nil, nil, invalid("%v", err)
+			}
+			mounts = append(mounts, tmpfs...)
+		case "image":
+			if source == "" {
+				return nil, nil, nil, invalid("source must name an image")
+			}
+			images = append(images, imageVolume{Source: source, Destination: target, ReadWrite: bools["rw"]})
+		}
+	}
+	return mounts, volumes, images, nil
+}
+
+// parseMountFields splits a --mount-type entry into its keys and values,
+// with each key in its canonical spelling.
+func parseMountFields(e string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for _, kv := range strings.Split(e, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		key := parts[0]
+		if canonical, ok := mountKeyAliases[key]; ok {
+			key = canonical
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid --mount-type %q: empty key", e)
+		}
+		if _, ok := fields[key]; ok {
+			return nil, fmt.Errorf("invalid --mount-type %q: %s is given twice", e, key)
+		}
+		switch {
+		case len(parts) == 2:
+			fields[key] = parts[1]
+		case mountBoolKeys[key]:
+			fields[key] = "true"
+		default:
+			return nil, fmt.Errorf("invalid --mount-type %q: expected %s=VALUE", e, key)
+		}
+	}
+	return fields, nil
+}
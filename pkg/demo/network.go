@@ -0,0 +1,157 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/containers/libpod/v2/pkg/bindings"
+	"github.com/containers/libpod/v2/pkg/bindings/network"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+)
+
+// ensureNetwork makes sure the named network exists, creating it with the
+// default bridge settings if needed.  It reports whether the network was
+// created here, so that cleanup leaves pre-existing networks alone.
+func ensureNetwork(conn context.Context, name string, out io.Writer) (bool, error) {
+	_, err := network.Inspect(conn, name)
+	if err == nil {
+		fmt.Fprintf(out, "Reusing existing network %s\n", name)
+		return false, nil
+	}
+	if !isNotFound(err) {
+		return false, fmt.Errorf("inspecting network %s: %w", name, err)
+	}
+
+	report, err := network.Create(conn, entities.NetworkCreateOptions{}, &name)
+	if err != nil {
+		// Someone else created it between the inspect and the create
+		if strings.Contains(err.Error(), "already exists") {
+			fmt.Fprintf(out, "Reusing existing network %s\n", name)
+			return false, nil
+		}
+		return false, fmt.Errorf("creating network %s: %w", name, err)
+	}
+	fmt.Fprintf(out, "Created network %s (%s)\n", name, report.Filename)
+	return true, nil
+}
+
+// checkNetworkDNS makes sure the named network resolves container names,
+// which needs the dnsname plugin in its CNI configuration.  Podman adds it
+// to new networks when the plugin is installed.
+func checkNetworkDNS(conn context.Context, name string) error {
+	reports, err := network.Inspect(conn, name)
+	if err != nil {
+		return fmt.Errorf("inspecting network %s: %w", name, err)
+	}
+	for _, report := range reports {
+		plugins, _ := report["plugins"].([]interface{})
+		for _, p := range plugins {
+			if plugin, ok := p.(map[string]interface{}); ok && plugin["type"] == "dnsname" {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("network %s has no DNS (the dnsname plugin), so --network-alias would not resolve", name)
+}
+
+// printNetworkAliases prints the aliases the container has on the named
+// network.  The inspect data in this version of the bindings has no
+// aliases, so the container's inspect endpoint is read directly.
+func printNetworkAliases(conn context.Context, id, name string, out io.Writer) error {
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return err
+	}
+	response, err := client.DoRequest(nil, http.MethodGet, "/containers/%s/json", nil, nil, id)
+	if err != nil {
+		return fmt.Errorf("inspecting container %s: %w", id, err)
+	}
+	var data struct {
+		NetworkSettings struct {
+			Networks map[string]struct {
+				Aliases []string
+			}
+		}
+	}
+	if err := response.Process(&data); err != nil {
+		return fmt.Errorf("inspecting container %s: %w", id, err)
+	}
+	aliases := data.NetworkSettings.Networks[name].Aliases
+	if len(aliases) == 0 {
+		fmt.Fprintf(out, "Container has no aliases on network %s; the service may predate network aliases (Podman 2.2)\n", name)
+		return nil
+	}
+	fmt.Fprintf(out, "Container aliases on network %s are %s\n", name, strings.Join(aliases, ", "))
+	return nil
+}
+
+// removeNetwork removes a network created by ensureNetwork.  A network that
+// is already gone is not an error.
+func removeNetwork(conn context.Context, name string) error {
+	force := false
+	if _, err := network.Remove(conn, name, &force); err != nil && !isNotFound(err) {
+		return fmt.Errorf("removing network %s: %w", name, err)
+	}
+	return nil
+}
+
+// printNetworkTable prints one line per network, like `podman network ls`.
+func printNetworkTable(out io.Writer, list []*entities.NetworkListReport) {
+	if len(list) == 0 {
+		fmt.Fprintln(out, "No networks found")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tPLUGINS")
+	for _, n := range list {
+		plugins := make([]string, 0, len(n.Plugins))
+		for _, p := range n.Plugins {
+			plugins = append(plugins, p.Network.Type)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", n.Name, n.CNIVersion, strings.Join(plugins, ","))
+	}
+	w.Flush()
+}
+
+// defaultNetwork is the network containers join when they are given none.
+// Like podman, pruneNetworks leaves it alone.
+const defaultNetwork = "podman"
+
+// pruneNetworks removes the networks that no container uses and returns
+// their names.
+//
+// This version of the service has no network prune endpoint, so we list
+// the networks and remove each one without force, which the service
+// refuses while containers are associated with it.
+func pruneNetworks(conn context.Context, logger *slog.Logger) ([]string, error) {
+	list, err := network.List(conn)
+	if err != nil {
+		return nil, fmt.Errorf("listing networks: %w", err)
+	}
+	var removed []string
+	force := false
+	for _, n := range list {
+		if n.Name == defaultNetwork {
+			continue
+		}
+		logger.Debug("network.Remove", "name", n.Name, "force", force)
+		reports, err := network.Remove(conn, n.Name, &force)
+		if err == nil && len(reports) > 0 && reports[0].Err != nil {
+			err = reports[0].Err
+		}
+		switch {
+		case err != nil && strings.Contains(err.Error(), "associated containers"):
+			logger.Debug("network in use", "name", n.Name)
+		case err != nil:
+			logger.Warn(fmt.Sprintf("could not prune network %s: %v", n.Name, err))
+		default:
+			removed = append(removed, n.Name)
+		}
+	}
+	return removed, nil
+}
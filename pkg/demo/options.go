@@ -0,0 +1,333 @@
+package demo
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/containers/libpod/v2/libpod/define"
+)
+
+// Options holds the settings for a Run.  Each field matches the
+// bindings-sample command-line flag of the same name, whose usage text
+// describes it.
+type Options struct {
+	Image   string
+	Socket  string
+	Remove  bool
+	Command []string
+	Env     []string
+	Volumes []string
+	Publish []string
+	Output  string
+
+	ConnectRetries int
+	ConnectTimeout time.Duration
+
+	// MaxParallelOps bounds how many calls to the service the concurrent
+	// steps, together, have in flight at once; less than 1 means no bound.
+	MaxParallelOps int
+
+	Username string
+	Password string
+	Authfile string
+
+	CleanupImage bool
+
+	WaitCondition define.ContainerStatus
+	WaitTimeout   time.Duration
+
+	Exec []string
+	Pod  string
+
+	// InfraImage and InfraCommand replace the image and command of the
+	// pod's infra container, which holds the namespaces the pod shares.
+	// NoInfra creates the pod without one, so it shares nothing.
+	InfraImage   string
+	InfraCommand []string
+	NoInfra      bool
+
+	// SecondImage runs a second container in the pod, with SecondCmd as
+	// its command, listening on SecondPort, which the first container
+	// then connects to over the pod's shared localhost.
+	SecondImage string
+	SecondCmd   []string
+	SecondPort  int
+
+	Stats time.Duration
+
+	Restart        string
+	RestartRetries uint
+	AutoRemove     bool
+
+	Name    string
+	Replace bool
+
+	ImageFilters []string
+	Dangling     bool
+	ImageLabels  []string
+
+	ListLimit int
+	All       bool
+
+	Build         string
+	Containerfile string
+	Tag           string
+	Push          bool
+
+	Verbose bool
+
+	Attach     bool
+	DetachKeys string
+
+	CreateVolume   string
+	Network        string
+	NetworkAliases []string
+	Pause          time.Duration
+	Rename         string
+	CopyIn         string
+	CopyOut        string
+
+	HealthCmd      string
+	HealthInterval time.Duration
+	HealthRetries  int
+	HealthTimeout  time.Duration
+
+	WatchEvents        bool
+	WatchState         bool
+	WatchStateInterval time.Duration
+
+	// StreamReconnects is how many times each log, stats or events stream
+	// is reopened on a new connection after the old one drops.
+	StreamReconnects int
+
+	User     string
+	GroupAdd []string
+	Workdir  string
+	Umask    string
+	TZ       string
+
+	Memory      string
+	CPUs        float64
+	ShmSize     string
+	OOMScoreAdj int
+
+	Export string
+	Save   string
+	Load   string
+
+	Quiet    bool
+	LogLevel slog.Level
+	DryRun   bool
+
+	// ValidateOnly stops a run once the options, the spec and the
+	// connection have been checked.  UsageErrors holds the problems the
+	// command line parser found, which it reports this way rather than
+	// exiting at the first.
+	ValidateOnly bool
+	UsageErrors  []string
+
+	// StrictFeatures makes an option the service is too old for an
+	// error, where it would otherwise be skipped with a warning.
+	StrictFeatures bool
+
+	PullRetries int
+	PullBackoff time.Duration
+	PullTimeout time.Duration
+	Arch        string
+	OS          string
+
+	Manifest string
+
+	Commit        string
+	CommitAuthor  string
+	CommitMessage string
+	CommitChanges []string
+
+	ContainerToImage string
+	Changes          []string
+
+	// Requires are the containers to start before this one.
+	// CreateDependency creates one more from the same image, to require.
+	Requires         []string
+	CreateDependency bool
+
+	Labels           []string
+	ContainerFilters []string
+	Tmpfs            []string
+	MountTypes       []string
+
+	Diff           bool
+	Top            bool
+	TopDescriptors string
+
+	Kill       bool
+	KillSignal syscall.Signal
+	// StopSignal stops the container gracefully; zero leaves it to the
+	// image, or SIGTERM.
+	StopSignal syscall.Signal
+	// StopTimeout is how long Podman waits after the stop signal before
+	// sending SIGKILL; zero kills the container immediately.
+	StopTimeout int
+
+	Init     bool
+	InitPath string
+
+	CapAdd  []string
+	CapDrop []string
+
+	Privileged     bool
+	SeccompProfile string
+	Apparmor       string
+	ReadOnly       bool
+	ReadOnlyTmpfs  bool
+
+	Sysctls []string
+	Ulimits []string
+
+	Hostname  string
+	DNS       []string
+	DNSSearch []string
+	AddHosts  []string
+
+	Entrypoint   string
+	NoEntrypoint bool
+	ShellParse   bool
+
+	Secrets       []string
+	CreateSecrets []string
+
+	GenerateSystemd    bool
+	UnitDir            string
+	GenerateKube       string
+	PlayKube           string
+	Down               bool
+	Checkpoint         string
+	LeaveRunning       bool
+	Restore            string
+	RestoreName        string
+	Replicas           int
+	Deadline           time.Duration
+	ManagePod          string
+	PodActions         string
+	PruneUntil         string
+	PruneLabels        []string
+	PruneVolumes       bool
+	PruneAllImages     bool
+	UpdateMemory       string
+	UpdateCPUs         float64
+	Mount              bool
+	Format             string
+	ImageFormat        string
+	Identity           string
+	TLSCert            string
+	TLSKey             string
+	TLSCA              string
+	Insecure           bool
+	PullPolicy         string
+	History            bool
+	HistoryWidth       int
+	EnvFiles           []string
+	EnvHost            bool
+	SecretEnv          []string
+	LabelFiles         []string
+	EventsLog          string
+	SocketWait         time.Duration
+	WaitConditions     []define.ContainerStatus
+	KeepImage          bool
+	NoPull             bool
+	RestartContainer   bool
+	RestartTimeout     int
+	CgroupNS           string
+	PidNS              string
+	IpcNS              string
+	UtsNS              string
+	UserNS             string
+	UIDMaps            []string
+	GIDMaps            []string
+	WaitForPort        string
+	WaitForPortTimeout time.Duration
+	StartupCmd         []string
+	StartupRetries     int
+	StartupInterval    time.Duration
+	ListNetworks       bool
+	ListVolumes        bool
+	PruneNetworks      bool
+	Annotations        []string
+	Devices            []string
+	NoExitCode         bool
+	LogTimestamps      bool
+	LogTail            string
+	LogSince           string
+	PullImages         []string
+	PullParallelism    int
+	FailFast           bool
+	AllTags            bool
+	AllTagsRun         string
+	Supervise          bool
+	MaxRestarts        int
+	SuperviseBackoff   time.Duration
+	SpecFile           string
+	Detach             bool
+	SdNotify           string
+	Rootfs             string
+	AttachExisting     string
+	CIDFile            string
+	InspectOut         string
+	Force              bool
+	DiskUsage          bool
+	Search             string
+	SearchLimit        int
+	SearchFilters      []string
+	Timings            bool
+}
+
+// parseFilters converts KEY=VALUE entries into the filter map taken by the
+// list bindings.  Values for the same key are grouped together.
+func parseFilters(flagName string, entries []string) (map[string][]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	filters := make(map[string][]string)
+	for _, e := range entries {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid %s %q: expected KEY=VALUE", flagName, e)
+		}
+		filters[kv[0]] = append(filters[kv[0]], kv[1])
+	}
+	return filters, nil
+}
+
+// imageListFilters adds --dangling and --image-label to filters, those of
+// --image-filter, without replacing any: the service only lists images
+// that match every filter.  --dangling contradicts --image-filter
+// dangling=false, so that is an error.
+func imageListFilters(filters map[string][]string, dangling bool, labels []string) (map[string][]string, error) {
+	if !dangling && len(labels) == 0 {
+		return filters, nil
+	}
+	merged := make(map[string][]string, len(filters)+2)
+	for k, v := range filters {
+		merged[k] = append([]string(nil), v...)
+	}
+	if dangling {
+		for _, v := range merged["dangling"] {
+			if v != "true" {
+				return nil, fmt.Errorf("--dangling contradicts --image-filter dangling=%s", v)
+			}
+		}
+		if len(merged["dangling"]) == 0 {
+			merged["dangling"] = []string{"true"}
+		}
+	}
+	for _, l := range labels {
+		if l == "" || strings.HasPrefix(l, "=") {
+			return nil, fmt.Errorf("invalid --image-label %q: expected KEY or KEY=VALUE", l)
+		}
+		merged["label"] = append(merged["label"], l)
+	}
+	return merged, nil
+}
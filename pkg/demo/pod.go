@@ -0,0 +1,241 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	"github.com/containers/libpod/v2/pkg/bindings/pods"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+	"github.com/containers/libpod/v2/pkg/specgen"
+	"github.com/docker/go-units"
+	"github.com/lsm5/bindings-sample/internal/retry"
+)
+
+// createPod creates the --pod for the container to join.  Containers in a
+// pod share the infra container's network, so any published ports have to
+// be set on the pod rather than on the container.  opts.InfraImage and
+// opts.InfraCommand customize the infra container; with opts.NoInfra there
+// is none and the pod shares no namespaces, so ports must stay on the
+// container.
+func createPod(conn context.Context, opts Options, ports []specgen.PortMapping) (string, error) {
+	p := specgen.NewPodSpecGenerator()
+	p.Name = opts.Pod
+	p.PortMappings = ports
+	p.NoInfra = opts.NoInfra
+	p.InfraImage = opts.InfraImage
+	p.InfraCommand = opts.InfraCommand
+	report, err := pods.CreatePodFromSpec(conn, p)
+	if err != nil {
+		return "", fmt.Errorf("creating pod %s: %w", opts.Pod, err)
+	}
+	return report.Id, nil
+}
+
+// replacePod removes the pod called name, and its containers, if there is
+// one.
+func replacePod(conn context.Context, name string, out io.Writer) error {
+	exists, err := pods.Exists(conn, name)
+	if err != nil {
+		return fmt.Errorf("checking for pod %s: %w", name, err)
+	}
+	if !exists {
+		return nil
+	}
+	fmt.Fprintf(out, "Replacing existing pod %s...\n", name)
+	return removePod(conn, name)
+}
+
+// removePod force-removes the pod and anything left in it.  A pod that is
+// already gone is not an error.
+func removePod(conn context.Context, id string) error {
+	force := true
+	if _, err := pods.Remove(conn, id, &force); err != nil && !isNotFound(err) {
+		return fmt.Errorf("removing pod %s: %w", id, err)
+	}
+	return nil
+}
+
+// printPod lists the containers that belong to the pod, and says which of
+// them, if any, is its infra container.
+func printPod(conn context.Context, id string, out io.Writer) error {
+	report, err := pods.Inspect(conn, id)
+	if err != nil {
+		return fmt.Errorf("inspecting pod %s: %w", id, err)
+	}
+	fmt.Fprintf(out, "Pod %s is %s with %d containers:\n", report.Name, report.State, report.NumContainers)
+	if report.CreateInfra {
+		fmt.Fprintf(out, "  Infra container %.12s holds the shared namespaces %s\n", report.InfraContainerID, strings.Join(report.SharedNamespaces, ", "))
+	} else {
+		fmt.Fprintln(out, "  No infra container was created, so nothing is shared")
+	}
+	for _, c := range report.Containers {
+		fmt.Fprintf(out, "  %.12s  %-20s  %s\n", c.ID, c.Name, c.State)
+	}
+	return nil
+}
+
+// podCheckTimeout is how long checkPodNetwork gives the second container
+// to start listening.
+const podCheckTimeout = 30 * time.Second
+
+// createSecond creates and starts the --second-image container in pod.
+// The image must already be there.  It shares the pod's namespaces, the
+// network one included, with the first container.
+func createSecond(conn context.Context, pod string, opts Options) (string, error) {
+	s := specgen.NewSpecGenerator(opts.SecondImage, false)
+	s.Pod = pod
+	s.Command = opts.SecondCmd
+	r, err := containers.CreateWithSpec(conn, s)
+	if err != nil {
+		return "", fmt.Errorf("creating the --second-image container: %w", err)
+	}
+	if err := containers.Start(conn, r.ID, nil); err != nil {
+		return r.ID, fmt.Errorf("starting the --second-image container %.12s: %w", r.ID, err)
+	}
+	return r.ID, nil
+}
+
+// checkPodNetwork connects from container id to port on localhost, which
+// the second container listens on, until it succeeds or podCheckTimeout
+// elapses, and returns how long that took.  It can only get through
+// because the pod's containers share one network namespace, and with it
+// one loopback interface.
+//
+// The connection is made with an exec of the shell's /dev/tcp, which bash
+// has, falling back to nc for images whose shell is not bash.
+func checkPodNetwork(ctx, conn context.Context, id string, port int) (time.Duration, error) {
+	start := time.Now()
+	probe := []string{"sh", "-c", fmt.Sprintf("(exec 3<>/dev/tcp/127.0.0.1/%d) 2>/dev/null || nc -z 127.0.0.1 %d", port, port)}
+	deadline, cancel := context.WithTimeout(ctx, podCheckTimeout)
+	defer cancel()
+	err := retry.Do(deadline, retry.Policy{Delay: portInterval}, func() error {
+		code, err := runExec(deadline, conn, id, probe, nil, io.Discard, io.Discard)
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		if code != 0 {
+			return fmt.Errorf("exit code %d", code)
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		return time.Since(start), nil
+	case ctx.Err() != nil:
+		return 0, ctx.Err()
+	}
+	return 0, fmt.Errorf("container %.12s cannot reach localhost:%d after %s: %w", id, port, podCheckTimeout, err)
+}
+
+// podActions are the operations --pod-actions can apply to a pod.
+var podActions = map[string]bool{
+	"start":   true,
+	"stop":    true,
+	"pause":   true,
+	"unpause": true,
+	"stats":   true,
+}
+
+// parsePodActions splits a comma-separated --pod-actions value such as
+// start,stats,stop.
+func parsePodActions(value string) ([]string, error) {
+	var actions []string
+	for _, a := range strings.Split(value, ",") {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" {
+			continue
+		}
+		if !podActions[a] {
+			return nil, fmt.Errorf("invalid --pod-actions %q: expected start, stop, pause, unpause or stats", a)
+		}
+		actions = append(actions, a)
+	}
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("--pod-actions must not be empty")
+	}
+	return actions, nil
+}
+
+// managePod applies actions in order to the existing pod name, printing
+// the pod's state after each one that changes it.
+func managePod(conn context.Context, name string, actions []string, stopTimeout int, out io.Writer) error {
+	exists, err := pods.Exists(conn, name)
+	if err != nil {
+		return fmt.Errorf("checking for pod %s: %w", name, err)
+	}
+	if !exists {
+		return fmt.Errorf("pod %s does not exist", name)
+	}
+
+	for _, action := range actions {
+		fmt.Fprintf(out, "Running %s on pod %s...\n", action, name)
+		switch action {
+		case "start":
+			_, err = pods.Start(conn, name)
+		case "stop":
+			_, err = pods.Stop(conn, name, &stopTimeout)
+		case "pause":
+			_, err = pods.Pause(conn, name)
+		case "unpause":
+			_, err = pods.Unpause(conn, name)
+		case "stats":
+			err = printPodStats(conn, name, out)
+		}
+		if err != nil {
+			return fmt.Errorf("running %s on pod %s: %w", action, name, err)
+		}
+		if action == "stats" {
+			continue
+		}
+		report, err := pods.Inspect(conn, name)
+		if err != nil {
+			return fmt.Errorf("inspecting pod %s: %w", name, err)
+		}
+		fmt.Fprintf(out, "Pod %s is now %s\n", name, report.State)
+	}
+	return nil
+}
+
+// printPodStats prints the resource usage of each container in the pod,
+// followed by the pod's total.  The service reports preformatted strings
+// such as "0.52%" and "1.18MB / 33.5GB", so the total is parsed back from
+// them.
+func printPodStats(conn context.Context, name string, out io.Writer) error {
+	reports, err := pods.Stats(conn, []string{name}, entities.PodStatsOptions{})
+	if err != nil {
+		return err
+	}
+
+	var (
+		cpu  float64
+		mem  int64
+		pids int
+	)
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tNAME\tCPU %\tMEM USAGE / LIMIT\tNET IO\tBLOCK IO\tPIDS")
+	for _, r := range reports {
+		fmt.Fprintf(w, "%.12s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.CID, r.Name, r.CPU, r.MemUsage, r.NetIO, r.BlockIO, r.PIDS)
+		if c, err := strconv.ParseFloat(strings.TrimSuffix(r.CPU, "%"), 64); err == nil {
+			cpu += c
+		}
+		if used := strings.SplitN(r.MemUsage, "/", 2)[0]; used != "" {
+			if m, err := units.FromHumanSize(strings.TrimSpace(used)); err == nil {
+				mem += m
+			}
+		}
+		if n, err := strconv.Atoi(r.PIDS); err == nil {
+			pids += n
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Pod total: CPU %.2f%%  MEM %s  PIDS %d\n", cpu, humanSize(mem), pids)
+	return nil
+}
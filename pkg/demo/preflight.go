@@ -0,0 +1,41 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	"github.com/containers/libpod/v2/pkg/bindings/images"
+)
+
+// preflight reports whether the image and a container called name are
+// already there, before anything is pulled or created.  Either check is
+// skipped when its name is empty.
+//
+// images.Exists and containers.Exists hit the exists endpoints, which
+// answer with a status code alone, so they are cheaper than an inspect and
+// need no telling a 404 apart from other failures.
+func preflight(conn context.Context, image, name string, out io.Writer) (imageFound, containerFound bool, err error) {
+	if image != "" {
+		if imageFound, err = images.Exists(conn, image); err != nil {
+			return false, false, fmt.Errorf("checking for image %s: %w", image, err)
+		}
+		if imageFound {
+			fmt.Fprintf(out, "Image %s is present locally\n", image)
+		} else {
+			fmt.Fprintf(out, "Image %s is not present locally\n", image)
+		}
+	}
+	if name != "" {
+		if containerFound, err = containers.Exists(conn, name); err != nil {
+			return false, false, fmt.Errorf("checking for container %s: %w", name, err)
+		}
+		if containerFound {
+			fmt.Fprintf(out, "Container %s already exists\n", name)
+		} else {
+			fmt.Fprintf(out, "No container is called %s yet\n", name)
+		}
+	}
+	return imageFound, containerFound, nil
+}
@@ -0,0 +1,90 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// This version of the service only answers a pull, push, build or wait
+// once it has finished, without any per-layer progress to render.  All we
+// can show is that the call is still busy: a spinner and running timer on
+// a terminal, or a status line every statusInterval when the output is a
+// file or pipe, where a spinner would only add noise.
+
+// statusInterval is how often drawProgress reports that a call is still
+// running when the output is not a terminal.
+const statusInterval = 10 * time.Second
+
+// progressWriter returns where withProgress should draw for opts: stderr,
+// unless the run is meant to be quiet.
+func progressWriter(opts Options) io.Writer {
+	if opts.Quiet || opts.LogLevel > slog.LevelInfo {
+		return io.Discard
+	}
+	return os.Stderr
+}
+
+// isTerminal reports whether w is a terminal.  golang.org/x/term is not
+// among our dependencies yet; its IsTerminal started out as this one.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
+// withProgress runs fn like withContext, drawing progress for label on w
+// until fn returns.
+func withProgress(ctx context.Context, w io.Writer, label string, fn func() error) error {
+	if w == io.Discard {
+		return withContext(ctx, fn)
+	}
+	done := make(chan struct{})
+	drawn := make(chan struct{})
+	go func() {
+		defer close(drawn)
+		drawProgress(w, label, isTerminal(w), done)
+	}()
+	err := withContext(ctx, fn)
+	close(done)
+	<-drawn
+	return err
+}
+
+// drawProgress draws until done is closed.  On a terminal the spinner is
+// cleared again once done, leaving nothing behind for calls that finish
+// within the first tick.
+func drawProgress(w io.Writer, label string, tty bool, done <-chan struct{}) {
+	interval := statusInterval
+	if tty {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	const spinner = `|/-\`
+	start := time.Now()
+	for ticks := 0; ; ticks++ {
+		select {
+		case <-done:
+			switch {
+			case ticks == 0:
+			case tty:
+				fmt.Fprint(w, "\r\033[K")
+			default:
+				fmt.Fprintf(w, "%s: done after %s\n", label, time.Since(start).Round(time.Second))
+			}
+			return
+		case <-ticker.C:
+			if tty {
+				fmt.Fprintf(w, "\r\033[K%s %c %s", label, spinner[ticks%len(spinner)], time.Since(start).Round(100*time.Millisecond))
+			} else {
+				fmt.Fprintf(w, "%s: still running after %s\n", label, time.Since(start).Round(time.Second))
+			}
+		}
+	}
+}
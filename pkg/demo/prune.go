@@ -0,0 +1,113 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	"github.com/containers/libpod/v2/pkg/bindings/images"
+	"github.com/containers/libpod/v2/pkg/bindings/volumes"
+)
+
+// pruneFilters builds the filters shared by the container and image prune
+// calls: until=DURATION or TIMESTAMP, and label=KEY[=VALUE].
+func pruneFilters(until string, labels []string) map[string][]string {
+	filters := make(map[string][]string)
+	if until != "" {
+		filters["until"] = []string{until}
+	}
+	if len(labels) > 0 {
+		filters["label"] = labels
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return filters
+}
+
+// Prune removes stopped containers and unused images matching
+// opts.PruneUntil and opts.PruneLabels, and with opts.PruneVolumes and
+// opts.PruneNetworks unused volumes and networks too.  It prints what was
+// removed and the space reclaimed.
+//
+// Only the container prune reports sizes in this version of the service;
+// image prune returns the IDs alone, so image space is not counted.
+func Prune(ctx context.Context, opts Options) error {
+	out, logger := commandOutput(opts.LogLevel)
+	conn, err := connect(ctx, opts, out, logger)
+	if err != nil {
+		return err
+	}
+	filters := pruneFilters(opts.PruneUntil, opts.PruneLabels)
+
+	logger.Info("Pruning containers...")
+	logger.Debug("containers.Prune", "filters", filters)
+	ctrReport, err := containers.Prune(conn, filters)
+	if err != nil {
+		return fmt.Errorf("pruning containers: %w", err)
+	}
+	var (
+		reclaimed int64
+		ctrCount  int
+	)
+	if ctrReport != nil {
+		ctrCount = len(ctrReport.ID)
+		ids := make([]string, 0, len(ctrReport.ID))
+		for id := range ctrReport.ID {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			fmt.Printf("Removed container %.12s (%s)\n", id, humanSize(ctrReport.ID[id]))
+			reclaimed += ctrReport.ID[id]
+		}
+		for id, err := range ctrReport.Err {
+			logger.Warn(fmt.Sprintf("could not prune container %.12s: %v", id, err))
+		}
+	}
+
+	logger.Info("Pruning images...")
+	logger.Debug("images.Prune", "all", opts.PruneAllImages, "filters", filters)
+	imageIDs, err := images.Prune(conn, &opts.PruneAllImages, filters)
+	if err != nil {
+		return fmt.Errorf("pruning images: %w", err)
+	}
+	for _, id := range imageIDs {
+		fmt.Printf("Removed image %.12s\n", id)
+	}
+
+	var volumeCount int
+	if opts.PruneVolumes {
+		logger.Info("Pruning volumes...")
+		logger.Debug("volumes.Prune")
+		reports, err := volumes.Prune(conn)
+		if err != nil {
+			return fmt.Errorf("pruning volumes: %w", err)
+		}
+		for _, r := range reports {
+			if r.Err != nil {
+				logger.Warn(fmt.Sprintf("could not prune volume %s: %v", r.Id, r.Err))
+				continue
+			}
+			fmt.Printf("Removed volume %s\n", r.Id)
+			volumeCount++
+		}
+	}
+
+	var networkNames []string
+	if opts.PruneNetworks {
+		logger.Info("Pruning networks...")
+		networkNames, err = pruneNetworks(conn, logger)
+		if err != nil {
+			return err
+		}
+		for _, name := range networkNames {
+			fmt.Printf("Removed network %s\n", name)
+		}
+	}
+
+	logger.Info(fmt.Sprintf("Removed %d containers, %d images, %d volumes and %d networks; containers reclaimed %s",
+		ctrCount, len(imageIDs), volumeCount, len(networkNames), humanSize(reclaimed)))
+	return nil
+}
@@ -0,0 +1,86 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// redialer hands the long-running streams (logs, stats and events) their
+// connection to the service, and replaces it when one of them finds that
+// it has dropped, e.g. because the service was restarted.  Each stream is
+// reconnected at most opts.StreamReconnects times.
+type redialer struct {
+	opts   Options
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	conn context.Context
+}
+
+func newRedialer(conn context.Context, opts Options, logger *slog.Logger) *redialer {
+	return &redialer{opts: opts, logger: logger, conn: conn}
+}
+
+// current returns the connection to open new streams on.
+func (r *redialer) current() context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.conn
+}
+
+// redial connects to the service again, just as at the start of the run,
+// and returns the new connection.  When several streams break at once,
+// only the first to notice reconnects: the others get its connection, as
+// stale is no longer the current one.
+func (r *redialer) redial(ctx context.Context, stale context.Context) (context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn != stale {
+		return r.conn, nil
+	}
+	conn, err := connectService(ctx, r.opts, io.Discard, r.logger)
+	if err != nil {
+		return nil, err
+	}
+	r.conn = conn
+	return conn, nil
+}
+
+// follow calls stream with the current connection and, each time it fails
+// because the connection broke, reconnects and calls it again, so that it
+// can pick up where it left off.  It gives up after opts.StreamReconnects
+// reconnections, or as soon as ctx is done, returning the last error.
+func (r *redialer) follow(ctx context.Context, what string, stream func(conn context.Context) error) error {
+	conn := r.current()
+	for attempt := 1; ; attempt++ {
+		err := stream(conn)
+		if err == nil || ctx.Err() != nil || !isBrokenConnection(err) {
+			return err
+		}
+		if attempt > r.opts.StreamReconnects {
+			return fmt.Errorf("%s: giving up after %d reconnections: %w", what, r.opts.StreamReconnects, err)
+		}
+		r.logger.Warn(fmt.Sprintf("The %s stream broke (%v); reconnecting, attempt %d/%d", what, err, attempt, r.opts.StreamReconnects))
+		if conn, err = r.redial(ctx, conn); err != nil {
+			return fmt.Errorf("reconnecting the %s stream: %w", what, err)
+		}
+	}
+}
+
+// isBrokenConnection reports whether err means the connection to the
+// service dropped part way, rather than the service refusing the request.
+// A stream that the service ends cleanly looks like any other that ends,
+// so it is not caught here.
+func isBrokenConnection(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr *net.OpError
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,33 @@
+package demo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsBrokenConnection(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"wrapped", fmt.Errorf("reading stats: %w", io.ErrUnexpectedEOF), true},
+		{"connection reset", &net.OpError{Op: "read", Net: "unix", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"broken pipe", fmt.Errorf("reading stats: %w", syscall.EPIPE), true},
+		{"clean end", io.EOF, false},
+		{"service error", errors.New("no such container"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBrokenConnection(tt.err); got != tt.want {
+				t.Errorf("isBrokenConnection(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
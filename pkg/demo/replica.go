@@ -0,0 +1,158 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/containers/libpod/v2/libpod/define"
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+	"github.com/containers/libpod/v2/pkg/specgen"
+	"github.com/lsm5/bindings-sample/internal/sem"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// replicaWorkers bounds how many replicas are created and started at once,
+// so a large --replicas does not flood the service, even with a generous
+// --max-parallel-ops.
+const replicaWorkers = 4
+
+// replica is one of the extra containers started by --replicas.
+type replica struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	State string `json:"state"`
+}
+
+// startReplicas creates and starts n copies of the container described by
+// s, and the image volumes, at most replicaWorkers at a time and within
+// what limit admits.  Named containers get a -1, -2, ... suffix.  Every
+// replica that was created is returned even when another one failed, so
+// that the caller can remove them all.  With replace, any existing
+// containers with those names are removed first.
+func startReplicas(ctx, conn context.Context, s *specgen.SpecGenerator, images []imageVolume, n int, replace bool, limit *sem.Limiter, out io.Writer) ([]*replica, error) {
+	if replace && s.Name != "" {
+		for i := 0; i < n; i++ {
+			if err := replaceContainer(conn, replicaName(s.Name, i), out); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	workers := semaphore.NewWeighted(replicaWorkers)
+	g, gctx := errgroup.WithContext(ctx)
+	created := make([]*replica, n)
+	for i := range created {
+		if err := workers.Acquire(gctx, 1); err != nil {
+			break
+		}
+		i := i
+		g.Go(func() error {
+			defer workers.Release(1)
+
+			// The spec is only read while it is sent, so the copies can
+			// share its slices and maps
+			spec := *s
+			if s.Name != "" {
+				spec.Name = replicaName(s.Name, i)
+			}
+			var r entities.ContainerCreateResponse
+			err := limit.Do(gctx, func() error {
+				var err error
+				r, err = createWithSpec(conn, &spec, specExtras{imageVolumes: images})
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("creating replica %d: %w", i+1, err)
+			}
+			created[i] = &replica{ID: r.ID, Name: spec.Name, State: "created"}
+			if err := limit.Do(gctx, func() error { return containers.Start(conn, r.ID, nil) }); err != nil {
+				return fmt.Errorf("starting replica %s: %w", r.ID, err)
+			}
+			created[i].State = "running"
+			return nil
+		})
+	}
+	err := g.Wait()
+
+	var replicas []*replica
+	for _, r := range created {
+		if r != nil {
+			replicas = append(replicas, r)
+		}
+	}
+	return replicas, err
+}
+
+// replicaName is the name of the i'th replica, counting from 0, of the
+// container called name.
+func replicaName(name string, i int) string {
+	return fmt.Sprintf("%s-%d", name, i+1)
+}
+
+// waitReplicas waits for every replica to reach condition.  The first
+// failure cancels the other waits.
+func waitReplicas(ctx, conn context.Context, replicas []*replica, condition define.ContainerStatus) error {
+	g, gctx := errgroup.WithContext(ctx)
+	for _, r := range replicas {
+		r := r
+		g.Go(func() error {
+			err := withContext(gctx, func() error {
+				_, err := containers.Wait(conn, r.ID, &condition)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("waiting for replica %s to be %s: %w", r.ID, condition, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// stopReplicas stops every replica, as many at once as limit admits, and
+// records the state each ends up in.  The stops wait for a slot however
+// long it takes, as the replicas must not be left running.
+func stopReplicas(conn context.Context, replicas []*replica, timeout uint, limit *sem.Limiter) error {
+	g := new(errgroup.Group)
+	for _, r := range replicas {
+		r := r
+		g.Go(func() error {
+			if err := limit.Do(context.Background(), func() error { return containers.Stop(conn, r.ID, &timeout) }); err != nil {
+				return fmt.Errorf("stopping replica %s: %w", r.ID, err)
+			}
+			data, err := containers.Inspect(conn, r.ID, nil)
+			if err != nil {
+				return fmt.Errorf("inspecting replica %s: %w", r.ID, err)
+			}
+			r.State = data.State.Status
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// removeReplicas removes every replica, carrying on past failures so that
+// one stuck replica does not leave the others behind.
+func removeReplicas(conn context.Context, replicas []*replica) error {
+	var firstErr error
+	for _, r := range replicas {
+		if err := removeContainer(conn, r.ID); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// printReplicas prints a table of the replicas and their states.
+func printReplicas(out io.Writer, replicas []*replica) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "REPLICA\tCONTAINER ID\tNAME\tSTATE")
+	for i, r := range replicas {
+		fmt.Fprintf(w, "%d\t%.12s\t%s\t%s\n", i+1, r.ID, r.Name, r.State)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,1593 @@
+// Package demo walks through the Podman Go bindings: it connects to the
+// service, pulls an image, and creates, runs and inspects a container.
+// Run performs the whole tutorial; the other exported functions are the
+// individual steps, for programs that want to drive them directly.
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/containers/libpod/v2/libpod/define"
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	"github.com/containers/libpod/v2/pkg/bindings/images"
+	"github.com/containers/libpod/v2/pkg/bindings/network"
+	"github.com/containers/libpod/v2/pkg/bindings/system"
+	"github.com/containers/libpod/v2/pkg/bindings/volumes"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+	"github.com/containers/libpod/v2/pkg/specgen"
+	"github.com/lsm5/bindings-sample/internal/sem"
+)
+
+// runSummary is the result of a run as printed by --output json.
+type runSummary struct {
+	ImageID     string        `json:"image_id"`
+	ContainerID string        `json:"container_id"`
+	ImageName   string        `json:"image_name"`
+	State       string        `json:"state"`
+	Replicas    []*replica    `json:"replicas,omitempty"`
+	DiskUsage   []diskUsage   `json:"disk_usage,omitempty"`
+	Timings     *timingReport `json:"timings,omitempty"`
+}
+
+// ExitCodeError is returned by Run when the container exited non-zero, so
+// that callers can exit with the same code.  opts.NoExitCode turns it off.
+type ExitCodeError int32
+
+func (e ExitCodeError) Error() string {
+	return fmt.Sprintf("container exited with code %d", int32(e))
+}
+
+// Failure is the kind of failure behind an error returned by Run or one of
+// the other commands, for callers that branch on it, e.g. to pick an exit
+// code.
+type Failure int
+
+const (
+	// FailureOther is any failure not listed below, such as a step that
+	// failed after the container was created.
+	FailureOther Failure = iota
+	// FailureSetup means the run never got going: an option was invalid
+	// or the service could not be reached.
+	FailureSetup
+	// FailureImageNotFound means the image, or a base image of --build,
+	// does not exist locally or in the registry.
+	FailureImageNotFound
+	// FailureNotCreated means the service refused to create the container.
+	FailureNotCreated
+	// FailureInvalid means --validate-only found problems; nothing was
+	// run.
+	FailureInvalid
+)
+
+// failureError tags an error with its Failure.
+type failureError struct {
+	failure Failure
+	err     error
+}
+
+func (e failureError) Error() string { return e.err.Error() }
+func (e failureError) Unwrap() error { return e.err }
+
+// failed tags err with f, unless it is nil or already tagged.
+func failed(f Failure, err error) error {
+	var tagged failureError
+	if err == nil || errors.As(err, &tagged) {
+		return err
+	}
+	return failureError{failure: f, err: err}
+}
+
+// FailureOf returns the kind of failure behind err.
+func FailureOf(err error) Failure {
+	var tagged failureError
+	if errors.As(err, &tagged) {
+		return tagged.failure
+	}
+	return FailureOther
+}
+
+// stepError tags an error with the step of Run it happened in.
+type stepError struct {
+	step string
+	err  error
+}
+
+func (e stepError) Error() string { return e.err.Error() }
+func (e stepError) Unwrap() error { return e.err }
+
+// StepOf returns the step of Run that err happened in, as announced in
+// its narrative, such as "Pulling image", or "" for an error that did not
+// come from Run.
+func StepOf(err error) string {
+	var tagged stepError
+	if errors.As(err, &tagged) {
+		return tagged.step
+	}
+	return ""
+}
+
+// withContext runs a blocking bindings call and returns early when ctx is
+// cancelled.  The bindings do not tie their HTTP requests to the context,
+// so without this a Ctrl-C during a long pull or wait would go unnoticed.
+func withContext(ctx context.Context, fn func() error) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- fn()
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isNotFound reports whether err is the service telling us that the
+// requested object does not exist.
+func isNotFound(err error) bool {
+	var apiErr entities.ErrorModel
+	return errors.As(err, &apiErr) && apiErr.Code() == http.StatusNotFound
+}
+
+// logOptions builds the options for containers.Logs from --timestamps,
+// --tail and --since; streamLogs adds the rest.
+func logOptions(opts Options) containers.LogOptions {
+	var logOpts containers.LogOptions
+	if opts.LogTimestamps {
+		logOpts.Timestamps = &opts.LogTimestamps
+	}
+	if opts.LogTail != "" && opts.LogTail != "all" {
+		logOpts.Tail = &opts.LogTail
+	}
+	if opts.LogSince != "" {
+		logOpts.Since = &opts.LogSince
+	}
+	return logOpts
+}
+
+// streamLogs follows the container's logs, copying them to stdout and
+// stderr until the container exits or ctx is cancelled.  If the connection
+// drops, the logs are followed again on a new one, from when the last line
+// arrived by this host's clock; the service compares that with the times
+// it stored, so a line or two either side of the break may be repeated or
+// missed.
+func streamLogs(ctx context.Context, streams *redialer, id string, logOpts containers.LogOptions, stdout, stderr io.Writer) error {
+	follow, wantStdout, wantStderr := true, true, true
+	logOpts.Follow = &follow
+	logOpts.Stdout = &wantStdout
+	logOpts.Stderr = &wantStderr
+
+	return streams.follow(ctx, "logs", func(conn context.Context) error {
+		// Logs blocks until the stream ends, so run it in the background
+		// and print lines as they arrive.  The channels are unbuffered, so
+		// every line has been received by the time Logs returns.
+		stdoutChan := make(chan string)
+		stderrChan := make(chan string)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- containers.Logs(conn, id, logOpts, stdoutChan, stderrChan)
+		}()
+
+		var last time.Time
+		for {
+			select {
+			case line := <-stdoutChan:
+				fmt.Fprint(stdout, line)
+				last = time.Now()
+			case line := <-stderrChan:
+				fmt.Fprint(stderr, line)
+				last = time.Now()
+			case err := <-errc:
+				if err != nil && !last.IsZero() {
+					since := last.Format(time.RFC3339Nano)
+					logOpts.Since = &since
+					logOpts.Tail = nil
+				}
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	})
+}
+
+// Run walks through the tutorial steps against the Podman service.  Each
+// step wraps the error returned by the failing bindings call so it is
+// clear where the run stopped.
+func Run(ctx context.Context, opts Options) (err error) {
+	// The narrative is only printed in text mode; in JSON mode stdout is
+	// reserved for the summary and container output goes to stderr.
+	// --quiet drops both, leaving only the final result.
+	out, ctrOut := io.Writer(os.Stdout), io.Writer(os.Stdout)
+	switch {
+	case opts.Quiet:
+		out, ctrOut = io.Discard, io.Discard
+	case opts.Output == "json":
+		out, ctrOut = io.Discard, os.Stderr
+	}
+	// The helpers print their narrative to out, which is info level
+	steps := newStepRecorder(newLogger(out, opts.LogLevel).Handler())
+	var handler slog.Handler = steps
+	var trace *eventLog
+	if opts.EventsLog != "" {
+		if trace, err = newEventLog(steps, opts.EventsLog); err != nil {
+			return err
+		}
+		handler = trace
+		defer func() {
+			if cerr := trace.close(err); cerr != nil {
+				fmt.Fprintln(os.Stderr, "Warning: writing --events-log:", cerr)
+			}
+		}()
+	}
+	logger := slog.New(handler)
+	if opts.LogLevel > slog.LevelInfo {
+		out = io.Discard
+	}
+	var timed *timings
+	if opts.Timings {
+		timed = newTimings()
+	}
+	// Shared by the steps that call the service concurrently
+	limit := sem.New(opts.MaxParallelOps)
+
+	// Give the whole run a time budget.  Registered first, so the error
+	// is annotated once the cleanup, which the expired context triggers,
+	// has run.
+	if opts.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Deadline)
+		stepAt := make(chan string, 1)
+		stopStep := context.AfterFunc(ctx, func() {
+			stepAt <- steps.last()
+		})
+		defer func() {
+			if !stopStep() && errors.Is(ctx.Err(), context.DeadlineExceeded) && err != nil {
+				step := <-stepAt
+				if step == "" {
+					step = "Connecting"
+				}
+				err = fmt.Errorf("exceeded --deadline of %s during %q: %w", opts.Deadline, step, err)
+			}
+			cancel()
+		}()
+	}
+
+	// Until connected, a failure is a bad option or an unreachable service
+	setup := true
+	defer func() {
+		if setup {
+			err = failed(FailureSetup, err)
+		}
+	}()
+
+	// Name the step a failure happened in.  The cleanup announces steps of
+	// its own, so the step is taken just before it starts, further down.
+	// Until the first step is announced, it is the checks or the connection.
+	failedStep, beforeSteps := "", "Checking the options"
+	defer func() {
+		if err == nil {
+			return
+		}
+		if failedStep == "" {
+			failedStep = steps.last()
+		}
+		if failedStep == "" {
+			failedStep = beforeSteps
+		}
+		err = stepError{step: failedStep, err: err}
+	}()
+
+	logger.Info("Welcome to Podman Go bindings tutorial")
+
+	// A built image replaces the pulled one
+	if opts.Build != "" {
+		opts.Image = opts.Tag
+	}
+
+	// Check the options up front so bad flags fail fast.  Under
+	// --validate-only every problem is collected, to be reported at once.
+	var problems []error
+	invalid := func(err error) bool {
+		if err == nil {
+			return false
+		}
+		problems = append(problems, err)
+		return !opts.ValidateOnly
+	}
+	for _, msg := range opts.UsageErrors {
+		problems = append(problems, errors.New(msg))
+	}
+
+	// Build the container spec
+	s, err := buildSpec(opts)
+	if invalid(err) {
+		return err
+	}
+	// Without --image, the image comes from the --spec file
+	if opts.Image == "" && s != nil {
+		opts.Image = s.Image
+	}
+	if opts.Privileged && len(opts.CapDrop) > 0 {
+		logger.Warn("--cap-drop has little effect on a --privileged container, which gets every capability")
+	}
+	if opts.Privileged && (opts.SeccompProfile != "" || opts.Apparmor != "") {
+		logger.Warn("--privileged disables seccomp and AppArmor confinement, overriding --seccomp-profile and --apparmor")
+	}
+	if opts.WaitForPort != "" && s != nil {
+		if err := checkWaitForPort(opts.WaitForPort, s.PortMappings); invalid(err) {
+			return err
+		}
+	}
+	if opts.ContainerToImage != "" {
+		if err := checkImportTag(opts.ContainerToImage); invalid(err) {
+			return err
+		}
+	}
+	for _, ref := range append([]string{opts.Image}, opts.PullImages...) {
+		if _, _, err := splitDigest(ref); invalid(err) {
+			return err
+		}
+	}
+	var allTagsRun string
+	if opts.AllTags {
+		if allTagsRun, err = allTagsReference(opts.Image, opts.AllTagsRun); invalid(err) {
+			return err
+		}
+	}
+	imageFilters, err := parseFilters("--image-filter", opts.ImageFilters)
+	if invalid(err) {
+		return err
+	}
+	if imageFilters, err = imageListFilters(imageFilters, opts.Dangling, opts.ImageLabels); invalid(err) {
+		return err
+	}
+	searchFilter, err := parseSearchFilters(opts.SearchFilters)
+	if invalid(err) {
+		return err
+	}
+	containerFilters, err := parseFilters("--filter", opts.ContainerFilters)
+	if invalid(err) {
+		return err
+	}
+	containerFormat, err := parseFormat("--format", opts.Format)
+	if invalid(err) {
+		return err
+	}
+	imageFormat, err := parseFormat("--image-format", opts.ImageFormat)
+	if invalid(err) {
+		return err
+	}
+	copyInSpec, err := parseCopy("--copy-in", opts.CopyIn)
+	if invalid(err) {
+		return err
+	}
+	if copyInSpec != nil {
+		if err := checkCopyIn(copyInSpec); invalid(err) {
+			return err
+		}
+	}
+	copyOutSpec, err := parseCopy("--copy-out", opts.CopyOut)
+	if invalid(err) {
+		return err
+	}
+	if copyOutSpec != nil {
+		if err := checkCopyOut(copyOutSpec); invalid(err) {
+			return err
+		}
+	}
+	if opts.Save != "" {
+		if err := checkSave("--save", opts.Save); invalid(err) {
+			return err
+		}
+	}
+	if opts.CIDFile != "" {
+		if err := checkCIDFile(opts.CIDFile, opts.Force); invalid(err) {
+			return err
+		}
+	}
+	if opts.InspectOut != "" {
+		if err := checkInspectOut(opts.InspectOut, opts.Force); invalid(err) {
+			return err
+		}
+	}
+	if opts.Load != "" {
+		if err := checkLoad(opts.Load); invalid(err) {
+			return err
+		}
+	}
+	if opts.Checkpoint != "" {
+		if err := checkSave("--checkpoint", opts.Checkpoint); invalid(err) {
+			return err
+		}
+	}
+
+	var updateMemory int64
+	if opts.UpdateMemory != "" {
+		if updateMemory, err = parseSize(opts.UpdateMemory); err != nil {
+			if err = fmt.Errorf("invalid --update-memory: %w", err); invalid(err) {
+				return err
+			}
+		}
+	}
+	var podActions []string
+	if opts.ManagePod != "" {
+		if podActions, err = parsePodActions(opts.PodActions); invalid(err) {
+			return err
+		}
+	}
+	var manifest *kubeManifest
+	if opts.PlayKube != "" {
+		if manifest, err = readKube(opts.PlayKube); invalid(err) {
+			return err
+		}
+	}
+
+	// Connecting is the last check; nothing is pulled or created
+	if opts.ValidateOnly {
+		return validateOnly(ctx, opts, s, problems, out, logger)
+	}
+
+	// Show what would be sent to the service and stop there
+	if opts.DryRun {
+		spec, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling the container spec: %w", err)
+		}
+		fmt.Println(string(spec))
+		return nil
+	}
+
+	// Connect to the Podman socket
+	beforeSteps = "Connecting"
+	endConnect := timed.start("connect")
+	conn, err := connect(ctx, opts, out, logger)
+	if err != nil {
+		return err
+	}
+	endConnect()
+	if err := checkFeatures(serviceVersion(conn), &opts, logger); err != nil {
+		return err
+	}
+	// Read after the check, which drops a --create-secret the service
+	// cannot take
+	newSecrets, err := readCreateSecrets(opts.CreateSecrets)
+	if err != nil {
+		return err
+	}
+	streams := newRedialer(conn, opts, logger)
+	if err := checkDevices(conn, s.Devices); err != nil {
+		return err
+	}
+	if err := checkSecrets(conn, opts); err != nil {
+		return err
+	}
+	setup = false
+
+	// Everything created from here on is pushed onto the cleanup stack,
+	// which removes it in reverse once the run is over, or straight away
+	// if it is interrupted.  Registered first, so the deferred calls
+	// below, such as stopping the event watch, run before it.
+	var cleanup cleanupStack
+	defer func() {
+		cleanup.unwind(ctx.Err() != nil, opts.Remove, logger)
+	}()
+	defer func() {
+		failedStep = steps.last()
+	}()
+
+	// When systemd runs the tutorial as a Type=notify unit, tell it once
+	// the container is up, and again as the run winds down.  Deferred
+	// after the cleanup, so STOPPING=1 is sent before it starts.
+	notify := func(state string) {
+		if opts.SdNotify == "ignore" {
+			return
+		}
+		sent, err := sdNotify(state)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("could not notify systemd: %v", err))
+		} else if sent {
+			logger.Debug("sd_notify", "state", state)
+		}
+	}
+	defer notify("STOPPING=1")
+	readySent := false
+	ready := func() {
+		if opts.SdNotify == "conmon" && !readySent {
+			notify("READY=1")
+			readySent = true
+		}
+	}
+
+	// Drive an existing pod through its lifecycle instead of the container
+	if opts.ManagePod != "" {
+		return managePod(conn, opts.ManagePod, podActions, opts.StopTimeout, out)
+	}
+
+	// Act on a container the sample did not create, and leave it be
+	if opts.AttachExisting != "" {
+		return attachExisting(ctx, conn, opts.AttachExisting, opts, logger, out, ctrOut)
+	}
+
+	// Play kube deploys whole pods from YAML instead of the container
+	if manifest != nil {
+		if opts.Down {
+			return downKube(conn, manifest, out)
+		}
+		if err := pullKubeImages(ctx, conn, manifest, opts.PullPolicy, pullOptions(opts), opts.PullRetries, opts.PullBackoff, logger, progressWriter(opts)); err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Deploying %s...", opts.PlayKube))
+		err := withContext(ctx, func() error {
+			return playKube(conn, opts.PlayKube, opts, out)
+		})
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Tear it down again with --play-kube %s --down", opts.PlayKube))
+		return nil
+	}
+
+	// Load images from an archive
+	if opts.Load != "" {
+		logger.Info(fmt.Sprintf("Loading images from %s...", opts.Load))
+		names, err := loadImage(conn, opts.Load)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Loaded images: %v", names))
+	}
+
+	// Preflight: see what is already there.  A built image is always
+	// built, and --pull images always pulled, so only --image is looked for.
+	rawImage := pullReference(opts.Image)
+	if rawImage != opts.Image {
+		logger.Info(fmt.Sprintf("Pulling %s by its digest alone; the tag is checked against it afterwards", rawImage))
+		s.Image = rawImage
+	}
+	checkImage := rawImage
+	if opts.Build != "" || len(opts.PullImages) > 0 || opts.Rootfs != "" {
+		checkImage = ""
+	}
+	logger.Debug("images.Exists and containers.Exists", "image", checkImage, "name", s.Name)
+	imageFound, containerFound, err := preflight(conn, checkImage, s.Name, out)
+	if err != nil {
+		return err
+	}
+	if containerFound && !opts.Replace {
+		return failed(FailureNotCreated, fmt.Errorf("container name %q is already in use; remove that container or pass --replace", s.Name))
+	}
+
+	// Search the registries, as one would before choosing what to pull
+	if opts.Search != "" {
+		logger.Info(fmt.Sprintf("Searching the registries for %s...", opts.Search))
+		logger.Debug("images.Search", "term", opts.Search, "limit", opts.SearchLimit, "filters", opts.SearchFilters)
+		if err := searchImages(conn, opts.Search, opts.SearchLimit, opts.SearchFilters, searchFilter, out); err != nil {
+			return err
+		}
+	}
+
+	// Build or pull the image
+	var (
+		imageIDs []string
+		pulled   bool
+	)
+	pullStep := "pull"
+	if opts.Build != "" {
+		pullStep = "build"
+	}
+	endPull := timed.start(pullStep)
+	if opts.Rootfs != "" {
+		logger.Info(fmt.Sprintf("Running from the root filesystem in %s, not pulling an image", s.Rootfs))
+	} else if opts.Build != "" {
+		if opts.NoPull {
+			if err := checkBaseImages(conn, opts.Build, opts.Containerfile); err != nil {
+				return err
+			}
+		}
+		logger.Info(fmt.Sprintf("Building image %s from %s...", rawImage, opts.Build))
+		err := withPullTimeout(ctx, "building "+rawImage, opts.PullTimeout, func(ctx context.Context) error {
+			return buildImage(ctx, conn, opts.Build, opts.Containerfile, rawImage, progressWriter(opts))
+		})
+		if err != nil {
+			return err
+		}
+	} else if len(opts.PullImages) > 0 {
+		// Pull every --pull image at once and run the first that arrived
+		logger.Info(fmt.Sprintf("Pulling %d images, at most %d at a time...", len(opts.PullImages), opts.PullParallelism))
+		refs := make([]string, len(opts.PullImages))
+		for i, ref := range opts.PullImages {
+			refs[i] = pullReference(ref)
+		}
+		results := pullImages(ctx, conn, refs, pullOptions(opts), opts.PullParallelism, limit, opts.FailFast, opts.PullRetries, opts.PullBackoff, opts.PullTimeout, logger)
+		printPullResults(out, results)
+		// The image that is run is pushed onto the cleanup stack below,
+		// like a single pulled one, unless the run stops here; the others
+		// are pushed now
+		var failures, others []pullResult
+		for _, r := range results {
+			switch {
+			case r.err != nil:
+				failures = append(failures, r)
+			case !pulled:
+				rawImage, imageIDs, pulled = r.image, r.ids, true
+			default:
+				others = append(others, r)
+			}
+		}
+		stop := ctx.Err() != nil || !pulled || (opts.FailFast && len(failures) > 0)
+		if stop && pulled {
+			others = append(others, pullResult{image: rawImage})
+		}
+		if opts.CleanupImage || (opts.Remove && !opts.KeepImage) {
+			for _, r := range others {
+				image := r.image
+				cleanup.pushAlways("removing image "+image, "Removed image "+image, func() error {
+					return removeImage(conn, image, out)
+				})
+			}
+		}
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case stop:
+			return fmt.Errorf("pulling image %s: %w", failures[0].image, failures[0].err)
+		case len(failures) > 0:
+			logger.Warn(fmt.Sprintf("%d of %d images could not be pulled", len(failures), len(results)))
+		}
+		logger.Info(fmt.Sprintf("Running the first image pulled, %s", rawImage))
+		s.Image = rawImage
+	} else if opts.AllTags {
+		// Pull the whole repository and run one of its tags
+		pullOpts := pullOptions(opts)
+		pullOpts.AllTags = true
+		logger.Info(fmt.Sprintf("Pulling every tag of %s...", rawImage))
+		logger.Debug("images.Pull", "image", rawImage, "allTags", true)
+		err = withPullTimeout(ctx, "pulling "+rawImage, opts.PullTimeout, func(ctx context.Context) error {
+			var err error
+			imageIDs, err = PullImage(ctx, conn, rawImage, pullOpts, opts.PullRetries, opts.PullBackoff, logger, progressWriter(opts))
+			return err
+		})
+		if err != nil {
+			err = fmt.Errorf("pulling every tag of %s: %w", rawImage, err)
+			if isImageNotFound(err) {
+				err = failed(FailureImageNotFound, err)
+			}
+			return err
+		}
+		tags, err := pulledTags(conn, rawImage, imageIDs)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Pulled %d tags of %s", len(tags), rawImage))
+		printPullResults(out, tags)
+		// The tag that is run is pushed onto the cleanup stack below, like
+		// a single pulled image; the others are pushed now
+		found := false
+		for _, t := range tags {
+			if t.image == allTagsRun {
+				found = true
+				continue
+			}
+			if opts.CleanupImage || (opts.Remove && !opts.KeepImage) {
+				image := t.image
+				cleanup.pushAlways("removing image "+image, "Removed image "+image, func() error {
+					return removeImage(conn, image, out)
+				})
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s has no tag %q to run; pick one of the above with --all-tags-run", rawImage, opts.AllTagsRun)
+		}
+		logger.Info(fmt.Sprintf("Running %s", allTagsRun))
+		rawImage, pulled = allTagsRun, true
+		s.Image = rawImage
+	} else {
+		pull, err := pullFor(rawImage, imageFound, opts.PullPolicy)
+		if err != nil {
+			return err
+		}
+		if !pull {
+			logger.Info(fmt.Sprintf("Image %s is already present, not pulling (--pull-policy=%s)", rawImage, opts.PullPolicy))
+		} else {
+			pullOpts := pullOptions(opts)
+			logger.Info("Pulling image...")
+			if pullOpts.Username != "" {
+				logger.Info(fmt.Sprintf("Authenticating as %s (password %s)", pullOpts.Username, maskPassword(pullOpts.Password)))
+			}
+			logger.Debug("images.Pull", "image", rawImage, "authfile", pullOpts.Authfile,
+				"username", pullOpts.Username, "password", maskPassword(pullOpts.Password))
+			err = withPullTimeout(ctx, "pulling "+rawImage, opts.PullTimeout, func(ctx context.Context) error {
+				var err error
+				imageIDs, err = PullImage(ctx, conn, rawImage, pullOpts, opts.PullRetries, opts.PullBackoff, logger, progressWriter(opts))
+				return err
+			})
+			if err != nil {
+				err = fmt.Errorf("pulling image %s: %w", rawImage, err)
+				if isImageNotFound(err) {
+					err = failed(FailureImageNotFound, err)
+				}
+				return err
+			}
+			pulled = true
+		}
+	}
+	endPull()
+
+	// Image inspect; a --rootfs container has none
+	var imageData *entities.ImageInspectReport
+	if opts.Rootfs == "" {
+		logger.Debug("images.GetImage", "image", rawImage)
+		imageData, err = images.GetImage(conn, rawImage, nil)
+		if err != nil {
+			err = fmt.Errorf("inspecting image %s: %w", rawImage, err)
+			if isNotFound(err) {
+				err = failed(FailureImageNotFound, err)
+			}
+			return err
+		}
+		trace.produced("image", imageData.ID)
+
+		// Remove the image last: with --cleanup-image, or with --rm if this
+		// run pulled it and --keep-image is not set
+		if opts.CleanupImage || (pulled && opts.Remove && !opts.KeepImage) {
+			cleanup.pushAlways("removing image "+rawImage, "Removed image "+rawImage, func() error {
+				return removeImage(conn, rawImage, out)
+			})
+		}
+		printImage(out, imageData, opts.Verbose)
+		pinned := opts.Image
+		if len(opts.PullImages) > 0 {
+			pinned = rawImage
+		}
+		checkPinnedDigest(conn, pinned, imageData, logger, out)
+		if opts.Arch != "" && imageData.Architecture != opts.Arch {
+			logger.Warn(fmt.Sprintf("pulled image is for %s/%s, not the requested %s", imageData.Os, imageData.Architecture, opts.Arch))
+		}
+
+		// Show how the image was built, layer by layer
+		if opts.History {
+			logger.Info(fmt.Sprintf("Listing the history of %s...", rawImage))
+			logger.Debug("images.History", "image", rawImage)
+			if err := printHistory(conn, rawImage, opts.HistoryWidth, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Save the image to an archive
+	if opts.Save != "" {
+		logger.Info(fmt.Sprintf("Saving %s to %s...", rawImage, opts.Save))
+		if err := saveImage(conn, rawImage, opts.Save); err != nil {
+			return err
+		}
+	}
+
+	// Tag the image under a new name and push it to a registry.  A built
+	// image already carries its --tag.
+	pushRef := rawImage
+	if opts.Tag != "" && opts.Build == "" {
+		repo, tag := splitReference(opts.Tag)
+		logger.Info(fmt.Sprintf("Tagging %s as %s:%s...", rawImage, repo, tag))
+		logger.Debug("images.Tag", "image", rawImage, "repo", repo, "tag", tag)
+		if err := images.Tag(conn, rawImage, tag, repo); err != nil {
+			return fmt.Errorf("tagging image %s: %w", rawImage, err)
+		}
+		pushRef = opts.Tag
+	}
+	if opts.Push {
+		// images.Push only returns once the push has finished
+		logger.Info(fmt.Sprintf("Pushing %s...", pushRef))
+		logger.Debug("images.Push", "source", pushRef, "destination", pushRef, "authfile", opts.Authfile,
+			"username", opts.Username, "password", maskPassword(opts.Password))
+		err := withProgress(ctx, progressWriter(opts), "Pushing "+pushRef, func() error {
+			return images.Push(conn, pushRef, pushRef, pushOptions(opts))
+		})
+		if err != nil {
+			return fmt.Errorf("pushing image %s: %w", pushRef, err)
+		}
+	}
+
+	// Manifest list: wrap the image in a list and optionally push it
+	if opts.Manifest != "" {
+		logger.Info(fmt.Sprintf("Creating manifest list %s...", opts.Manifest))
+		if err := createManifest(conn, opts.Manifest, rawImage, out); err != nil {
+			return err
+		}
+		cleanup.push("manifest list", "manifest list "+opts.Manifest, func() error {
+			return removeManifest(conn, opts.Manifest)
+		})
+
+		if opts.Push {
+			logger.Info(fmt.Sprintf("Pushing manifest list %s...", opts.Manifest))
+			err := withContext(ctx, func() error {
+				return pushManifest(conn, opts.Manifest)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// List images, optionally filtered (e.g. reference=fedora*)
+	logger.Debug("images.List", "filters", imageFilters)
+	imageSummary, err := images.List(conn, nil, imageFilters)
+	if err != nil {
+		return fmt.Errorf("listing images: %w", err)
+	}
+	switch {
+	case imageFormat != nil:
+		if err := printImagesFormatted(out, imageFormat, imageSummary); err != nil {
+			return err
+		}
+	case opts.Dangling || len(opts.ImageLabels) > 0:
+		// Dangling images have no names to show, and these lists are
+		// mostly wanted to see what removing them would free
+		printImageSizes(out, imageSummary)
+	default:
+		var names []string
+		for _, i := range imageSummary {
+			names = append(names, i.RepoTags...)
+		}
+		logger.Info(fmt.Sprintf("%d images match: %v", len(imageSummary), names))
+	}
+
+	// Volume create: a named volume that outlives the container
+	if opts.CreateVolume != "" {
+		if opts.Replace {
+			if err := replaceVolume(conn, opts.CreateVolume, out); err != nil {
+				return err
+			}
+		}
+		logger.Info(fmt.Sprintf("Creating volume %s...", opts.CreateVolume))
+		if err := createVolume(conn, opts.CreateVolume, out); err != nil {
+			return err
+		}
+		cleanup.push("volume", "volume "+opts.CreateVolume, func() error {
+			return removeVolume(conn, opts.CreateVolume, out)
+		})
+	}
+
+	// Network create: reuse the network if it already exists
+	if opts.Network != "" {
+		created, err := ensureNetwork(conn, opts.Network, out)
+		if err != nil {
+			return err
+		}
+
+		// Only remove what we created
+		if created {
+			cleanup.push("network", "network "+opts.Network, func() error {
+				return removeNetwork(conn, opts.Network)
+			})
+		}
+		if len(opts.NetworkAliases) > 0 {
+			if err := checkNetworkDNS(conn, opts.Network); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Pod create: the container joins the pod instead of running alone
+	if opts.Pod != "" {
+		if opts.Replace {
+			if err := replacePod(conn, opts.Pod, out); err != nil {
+				return err
+			}
+		}
+		logger.Info(fmt.Sprintf("Creating pod %s...", opts.Pod))
+		var podPorts []specgen.PortMapping
+		if !opts.NoInfra {
+			podPorts, s.PortMappings = s.PortMappings, nil
+		}
+		podID, err := createPod(conn, opts, podPorts)
+		if err != nil {
+			return err
+		}
+		trace.produced("pod", podID)
+		s.Pod = podID
+		cleanup.push("pod", "pod "+podID, func() error {
+			return removePod(conn, podID)
+		})
+	}
+
+	// Create a container for this one to require.  Pushed before it, so
+	// the container that requires it is removed first, as Podman insists.
+	if opts.CreateDependency {
+		logger.Info(fmt.Sprintf("Creating a dependency container from %s...", s.Image))
+		logger.Debug("containers.CreateWithSpec", "image", s.Image, "pod", s.Pod, "command", []string{"sleep", "infinity"})
+		depID, err := createDependency(conn, s)
+		if err != nil {
+			return failed(FailureNotCreated, err)
+		}
+		trace.produced("container", depID)
+		cleanup.push("dependency container", "dependency container "+depID, func() error {
+			return removeContainer(conn, depID)
+		})
+		opts.Requires = append(opts.Requires, depID)
+	}
+
+	// Secret create: the container mounts these alongside any --secret
+	// secrets that already exist
+	for _, secret := range newSecrets {
+		name := secret.name
+		logger.Info(fmt.Sprintf("Creating secret %s...", name))
+		logger.Debug("secrets.Create", "name", name, "size", len(secret.data))
+		id, err := createSecret(conn, name, secret.data)
+		if err != nil {
+			return err
+		}
+		trace.produced("secret", id)
+		cleanup.push("secret", "secret "+name, func() error {
+			return removeSecret(conn, name)
+		})
+	}
+
+	// Container create
+	if err := checkNamespaceContainers(conn, s); err != nil {
+		return failed(FailureNotCreated, err)
+	}
+	if err := checkRequires(conn, opts.Requires); err != nil {
+		return failed(FailureNotCreated, err)
+	}
+	extras, err := buildExtras(opts)
+	if err != nil {
+		return err
+	}
+	created := time.Now()
+	logger.Debug("containers.CreateWithSpec", "image", s.Image, "name", s.Name, "command", s.Command, "pod", s.Pod)
+	endCreate := timed.start("create")
+	r, err := createContainer(conn, s, extras, opts.Replace, out)
+	if err != nil {
+		return err
+	}
+	endCreate()
+
+	cleanup.push("container", "container "+r.ID, func() error {
+		return removeContainer(conn, r.ID)
+	})
+
+	// Record the container's ID for scripts; --rm removes the file with
+	// the container
+	if opts.CIDFile != "" {
+		if err := writeCIDFile(opts.CIDFile, r.ID, opts.Force); err != nil {
+			return err
+		}
+		cleanup.push("cidfile", "cidfile "+opts.CIDFile, func() error {
+			return os.Remove(opts.CIDFile)
+		})
+	}
+
+	// Watch the container's events until the run is over.  This stops
+	// before the container is removed, so its removal is not shown.
+	if opts.WatchEvents {
+		stopEvents := watchEvents(streams, r.ID, created, out)
+		defer stopEvents()
+	}
+
+	// Print the container's state as it changes, from created until it
+	// exits
+	if opts.WatchState {
+		stopState := watchState(conn, r.ID, opts.WatchStateInterval, out)
+		defer stopState()
+	}
+
+	// Rename the container; cleanup goes by ID, so it is unaffected
+	if opts.Rename != "" {
+		logger.Info(fmt.Sprintf("Renaming the container to %s...", opts.Rename))
+		if err := renameContainer(conn, r.ID, opts.Rename, out); err != nil {
+			return err
+		}
+	}
+
+	// Generate systemd units that recreate the container at boot
+	if opts.GenerateSystemd {
+		logger.Info(fmt.Sprintf("Generating systemd units in %s...", opts.UnitDir))
+		paths, err := generateSystemd(conn, r.ID, opts.UnitDir)
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			logger.Info(fmt.Sprintf("Wrote %s", p))
+		}
+	}
+
+	// Describe the container, or the whole pod, as Kubernetes YAML
+	if opts.GenerateKube != "" {
+		target := r.ID
+		if s.Pod != "" {
+			target = s.Pod
+		}
+		logger.Info(fmt.Sprintf("Writing Kubernetes YAML to %s...", opts.GenerateKube))
+		if err := generateKube(conn, target, opts.GenerateKube); err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Recreate it later with --play-kube %s or podman play kube %s", opts.GenerateKube, opts.GenerateKube))
+	}
+
+	// Copy a host file into the container before it starts
+	if copyInSpec != nil {
+		logger.Info(fmt.Sprintf("Copying %s into the container at %s...", copyInSpec.src, copyInSpec.dst))
+		if err := copyIn(conn, r.ID, copyInSpec); err != nil {
+			return err
+		}
+	}
+
+	// Mount the root filesystem so it can be inspected from the host.
+	// Pushed after the container, so it is unmounted first.
+	if opts.Mount {
+		logger.Info("Mounting the container's root filesystem...")
+		logger.Debug("containers.Mount", "id", r.ID)
+		if _, err := mountContainer(conn, r.ID, out); err != nil {
+			return err
+		}
+		cleanup.pushAlways("unmounting the container's root filesystem", "Unmounted the container's root filesystem", func() error {
+			if err := containers.Unmount(conn, r.ID); err != nil && !isNotFound(err) {
+				return fmt.Errorf("unmounting container %s: %w", r.ID, err)
+			}
+			return nil
+		})
+	}
+
+	// Container start
+	if opts.Rootfs != "" {
+		logger.Info("Starting the container...")
+	} else {
+		logger.Info(fmt.Sprintf("Starting %s container...", rawImage))
+	}
+	trace.produced("container", r.ID)
+	logger.Debug("containers.Start", "id", r.ID)
+	endStart := timed.start("start")
+	err = containers.Start(conn, r.ID, nil)
+	if err != nil {
+		return fmt.Errorf("starting container %s: %w", r.ID, err)
+	}
+	endStart()
+	if len(opts.Requires) > 0 {
+		if err := printDependencies(conn, opts.Requires, out); err != nil {
+			logger.Warn(fmt.Sprintf("could not check the dependencies: %v", err))
+		}
+	}
+	// A container not waited for until running is as ready as it gets
+	if opts.Detach || opts.WaitCondition != define.ContainerStateRunning {
+		ready()
+	}
+
+	// Leave the container running, like podman run -d.  --detach turns
+	// --rm off, so the cleanup below keeps everything.
+	if opts.Detach {
+		logger.Info("Container started; detaching")
+		fmt.Println(r.ID)
+		return nil
+	}
+
+	// Stream container logs in the background; the stream ends once the
+	// container stops.  When attaching, the output arrives that way instead.
+	logsErr := make(chan error, 1)
+	if opts.Attach {
+		logsErr <- nil
+	} else {
+		go func() {
+			logsErr <- streamLogs(ctx, streams, r.ID, logOptions(opts), ctrOut, os.Stderr)
+		}()
+	}
+
+	// Start the other replicas alongside it.  The steps below only act on
+	// the first container; the replicas are waited for, stopped and
+	// removed with it.
+	var replicas []*replica
+	if opts.Replicas > 1 {
+		logger.Info(fmt.Sprintf("Starting %d more replicas...", opts.Replicas-1))
+		replicas, err = startReplicas(ctx, conn, s, extras.imageVolumes, opts.Replicas-1, opts.Replace, limit, out)
+		// Pushed even on failure, so that the replicas which did start
+		// are removed
+		cleanup.push("replicas", fmt.Sprintf("%d replicas", len(replicas)), func() error {
+			return removeReplicas(conn, replicas)
+		})
+		if err != nil {
+			return err
+		}
+		for i, rep := range replicas {
+			trace.produced(fmt.Sprintf("replica %d", i+1), rep.ID)
+		}
+	}
+
+	// Wait for the container to reach the requested state
+	waitCtx, cancelWait := ctx, context.CancelFunc(func() {})
+	if opts.WaitTimeout > 0 {
+		waitCtx, cancelWait = context.WithTimeout(ctx, opts.WaitTimeout)
+	}
+	conditions := opts.WaitConditions
+	if len(conditions) == 0 {
+		conditions = []define.ContainerStatus{opts.WaitCondition}
+	}
+	var (
+		exitCode int32
+		reached  define.ContainerStatus
+		gone     bool
+	)
+	logger.Debug("containers.Wait", "id", r.ID, "conditions", conditions, "timeout", opts.WaitTimeout)
+	names := make([]string, len(conditions))
+	for i, c := range conditions {
+		names[i] = c.String()
+	}
+	label := "Waiting for the container to be " + strings.Join(names, " or ")
+	endWait := timed.start("wait")
+	if opts.Supervise {
+		// Each new container gets its own log stream, once the last one's
+		// has ended.  The cleanup above goes by r.ID, so it removes the
+		// newest container.
+		exitCode, err = supervise(waitCtx, conn, &r.ID, s, extras, opts.MaxRestarts, opts.SuperviseBackoff, logger, out, func(id string) {
+			if err := <-logsErr; err != nil {
+				logger.Warn(fmt.Sprintf("could not stream logs of the last container: %v", err))
+			}
+			trace.produced("container", id)
+			if opts.CIDFile != "" {
+				if err := writeCIDFile(opts.CIDFile, id, true); err != nil {
+					logger.Warn(fmt.Sprintf("could not update the cidfile: %v", err))
+				}
+			}
+			go func() {
+				logsErr <- streamLogs(ctx, streams, id, logOptions(opts), ctrOut, os.Stderr)
+			}()
+		})
+		reached = define.ContainerStateExited
+	} else {
+		err = withProgress(waitCtx, progressWriter(opts), label, func() error {
+			var err error
+			reached, exitCode, err = waitContainer(conn, r.ID, conditions)
+			// With --auto-remove a short-lived container can be removed
+			// before the wait begins.  Later versions of Wait take an
+			// option to ignore that; this one has none, so the 404 is
+			// taken as the exit that led to it, and the code is found
+			// in the container's died event instead.
+			if err != nil && opts.AutoRemove && isNotFound(err) {
+				gone = true
+				reached = define.ContainerStateExited
+				exitCode, err = exitCodeFromEvents(conn, r.ID, created)
+			}
+			return err
+		})
+	}
+	cancelWait()
+	if err != nil {
+		return fmt.Errorf("waiting for container %s to be %v: %w", r.ID, conditions, err)
+	}
+	endWait()
+	if len(conditions) > 1 {
+		logger.Info(fmt.Sprintf("Container is %s", reached))
+	}
+	if len(replicas) > 0 {
+		waitCtx, cancelWait := ctx, context.CancelFunc(func() {})
+		if opts.WaitTimeout > 0 {
+			waitCtx, cancelWait = context.WithTimeout(ctx, opts.WaitTimeout)
+		}
+		err := waitReplicas(waitCtx, conn, replicas, reached)
+		cancelWait()
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("All %d replicas are %s", len(replicas)+1, reached))
+	}
+	if gone {
+		logger.Info("Container was already gone when the wait began; the service removed it when it exited")
+	}
+	if reached == define.ContainerStateExited {
+		logger.Info(fmt.Sprintf("Container exited with code %d", exitCode))
+	}
+
+	// The summary and exit code that end the run, once the container is
+	// in the given state
+	finish := func(imageName, state string, usage []diskUsage) error {
+		if opts.Output == "json" {
+			summary := runSummary{
+				ContainerID: r.ID,
+				ImageName:   imageName,
+				State:       state,
+				Replicas:    replicas,
+				DiskUsage:   usage,
+				Timings:     timed.report(),
+			}
+			if len(imageIDs) > 0 {
+				summary.ImageID = imageIDs[0]
+			} else if imageData != nil {
+				summary.ImageID = imageData.ID
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(summary); err != nil {
+				return fmt.Errorf("writing summary: %w", err)
+			}
+		} else if opts.Quiet {
+			fmt.Println(r.ID)
+		}
+		if timed != nil && opts.Output != "json" {
+			printTimings(out, timed.report())
+		}
+
+		// Exit like podman run would.  Cleanup runs after this, and only
+		// reports its failures, so the container's code still wins.
+		if reached == define.ContainerStateExited && exitCode != 0 && !opts.NoExitCode {
+			return ExitCodeError(exitCode)
+		}
+		return nil
+	}
+
+	// With --auto-remove the service removes the container as it exits,
+	// so there is nothing left to inspect or stop.  Its logs may have
+	// gone with it before they could be read.
+	if opts.AutoRemove {
+		if err := <-logsErr; err != nil && !isNotFound(err) {
+			return fmt.Errorf("streaming logs of container %s: %w", r.ID, err)
+		}
+		logger.Info("Container was removed by the service (--auto-remove)")
+		return finish(s.Image, "removed", nil)
+	}
+
+	// Wait for the healthcheck to pass
+	if opts.HealthCmd != "" {
+		logger.Info(fmt.Sprintf("Waiting up to %s for the container to become healthy...", opts.HealthTimeout))
+		if err := waitHealthy(ctx, conn, r.ID, opts.HealthInterval, opts.HealthTimeout, out); err != nil {
+			return err
+		}
+	}
+
+	// Wait for the published port to accept connections
+	if opts.WaitForPort != "" {
+		logger.Info(fmt.Sprintf("Waiting up to %s for %s to accept connections...", opts.WaitForPortTimeout, opts.WaitForPort))
+		ready, err := waitForPort(ctx, opts.WaitForPort, opts.WaitForPortTimeout)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("%s is accepting connections after %s", opts.WaitForPort, ready.Round(10*time.Millisecond)))
+	}
+
+	// Run a second container in the pod, and reach it from the first over
+	// the localhost they share
+	if opts.SecondImage != "" {
+		pull, err := needPull(conn, opts.SecondImage, opts.PullPolicy)
+		if err != nil {
+			return err
+		}
+		if pull {
+			logger.Info(fmt.Sprintf("Pulling image %s...", opts.SecondImage))
+			if _, err := PullImage(ctx, conn, opts.SecondImage, pullOptions(opts), opts.PullRetries, opts.PullBackoff, logger, progressWriter(opts)); err != nil {
+				return fmt.Errorf("pulling image %s: %w", opts.SecondImage, err)
+			}
+			if opts.CleanupImage || (opts.Remove && !opts.KeepImage) {
+				cleanup.pushAlways("removing image "+opts.SecondImage, "Removed image "+opts.SecondImage, func() error {
+					return removeImage(conn, opts.SecondImage, out)
+				})
+			}
+		}
+		logger.Info(fmt.Sprintf("Starting a second container from %s in pod %s...", opts.SecondImage, opts.Pod))
+		logger.Debug("containers.CreateWithSpec", "image", opts.SecondImage, "pod", s.Pod, "command", opts.SecondCmd)
+		secondID, err := createSecond(conn, s.Pod, opts)
+		// Pushed after the first container, so it is removed before it,
+		// and both before the pod
+		if secondID != "" {
+			trace.produced("second container", secondID)
+			cleanup.push("second container", "container "+secondID, func() error {
+				return removeContainer(conn, secondID)
+			})
+		}
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Connecting from container %.12s to localhost:%d, where container %.12s listens...", r.ID, opts.SecondPort, secondID))
+		took, err := checkPodNetwork(ctx, conn, r.ID, opts.SecondPort)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Reached the second container over the pod's shared network after %s", took.Round(10*time.Millisecond)))
+	}
+
+	// Run the startup command until it succeeds
+	if len(opts.StartupCmd) > 0 {
+		logger.Info(fmt.Sprintf("Running %v in the container until it succeeds, up to %d times...", opts.StartupCmd, opts.StartupRetries))
+		logger.Debug("containers.ExecCreate", "id", r.ID, "cmd", opts.StartupCmd)
+		attempts, err := waitStartup(ctx, conn, r.ID, opts.StartupCmd, opts.StartupRetries, opts.StartupInterval, out)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Startup command succeeded on attempt %d of %d", attempts, opts.StartupRetries))
+	}
+	if reached == define.ContainerStateRunning {
+		ready()
+	}
+
+	// Attach to the container until it exits or we detach from it
+	if opts.Attach {
+		logger.Info(fmt.Sprintf("Attaching to the container, detach with %s...", opts.DetachKeys))
+		if err := attachContainer(ctx, conn, r.ID, opts.DetachKeys); err != nil {
+			return err
+		}
+	}
+
+	// Run a command inside the running container
+	if len(opts.Exec) > 0 {
+		logger.Info(fmt.Sprintf("Running %v in the container...", opts.Exec))
+		execCode, err := runExec(ctx, conn, r.ID, opts.Exec, execInput(), ctrOut, os.Stderr)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Exec session exited with code %d", execCode))
+		if len(opts.SecretEnv) > 0 {
+			logger.Info("Checking the --secret-env variables are set...")
+			if err := checkSecretEnv(ctx, conn, r.ID, opts.SecretEnv, out); err != nil {
+				return err
+			}
+		}
+		if opts.TZ != "" {
+			logger.Info(fmt.Sprintf("Checking the container's clock is on %s time...", opts.TZ))
+			if _, err := runExec(ctx, conn, r.ID, []string{"date"}, nil, ctrOut, os.Stderr); err != nil {
+				logger.Warn(fmt.Sprintf("could not run date in the container: %v", err))
+			}
+		}
+	}
+
+	// Pause the container, then let it carry on
+	if opts.Pause > 0 {
+		logger.Info(fmt.Sprintf("Pausing the container for %s...", opts.Pause))
+		if err := pauseContainer(ctx, conn, r.ID, opts.Pause, out); err != nil {
+			return err
+		}
+	}
+
+	// Restart the container in a single call, unlike a restart policy
+	if opts.RestartContainer {
+		logger.Info("Restarting the container...")
+		logger.Debug("containers.Restart", "id", r.ID, "timeout", opts.RestartTimeout)
+		err := withContext(ctx, func() error {
+			return restartContainer(conn, r.ID, opts.RestartTimeout, out)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Change the resource limits without recreating the container
+	if opts.UpdateMemory != "" || opts.UpdateCPUs > 0 {
+		logger.Info("Updating the container's resource limits...")
+		logger.Debug("containers.Update", "id", r.ID, "memory", updateMemory, "cpus", opts.UpdateCPUs)
+		if err := updateResources(conn, r.ID, updateMemory, opts.UpdateCPUs, out); err != nil {
+			return err
+		}
+	}
+
+	// List the processes running in the container
+	if opts.Top {
+		logger.Info("Listing the container's processes...")
+		if err := printTop(conn, r.ID, strings.Split(opts.TopDescriptors, ","), out); err != nil {
+			return err
+		}
+	}
+
+	// Sample the container's resource usage
+	if opts.Stats > 0 {
+		logger.Info(fmt.Sprintf("Collecting stats for %s...", opts.Stats))
+		if err := collectStats(ctx, streams, r.ID, opts.Stats, out); err != nil {
+			return err
+		}
+	}
+
+	// Copy a path out of the container
+	if copyOutSpec != nil {
+		logger.Info(fmt.Sprintf("Copying %s out of the container to %s...", copyOutSpec.src, copyOutSpec.dst))
+		if err := copyOut(conn, r.ID, copyOutSpec); err != nil {
+			return err
+		}
+	}
+
+	// Export the container's filesystem
+	if opts.Export != "" {
+		logger.Info(fmt.Sprintf("Exporting the container to %s...", opts.Export))
+		size, err := exportContainer(conn, r.ID, opts.Export)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Exported %d bytes to %s", size, opts.Export))
+	}
+
+	// Show what the container changed
+	if opts.Diff {
+		logger.Info("Diffing the container filesystem...")
+		if err := printDiff(conn, r.ID, out); err != nil {
+			return err
+		}
+	}
+
+	// Commit the container to a new image
+	if opts.Commit != "" {
+		logger.Info(fmt.Sprintf("Committing the container to %s...", opts.Commit))
+		id, err := commitContainer(conn, r.ID, opts.Commit, opts, out)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Committed image ID is %.12s", id))
+	}
+
+	// Turn the container's filesystem into an image of its own
+	if opts.ContainerToImage != "" {
+		logger.Info(fmt.Sprintf("Exporting the container and importing it as %s...", opts.ContainerToImage))
+		logger.Debug("containers.Export and images.Import", "id", r.ID, "reference", opts.ContainerToImage, "changes", opts.Changes)
+		id, err := containerToImage(conn, r.ID, opts.ContainerToImage, opts.Changes, out)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Imported image ID is %.12s", id))
+	}
+
+	// Checkpoint the container to an archive and bring it back from one
+	if opts.Checkpoint != "" {
+		logger.Info(fmt.Sprintf("Checkpointing the container to %s...", opts.Checkpoint))
+		size, err := checkpointContainer(conn, r.ID, opts.Checkpoint, opts.LeaveRunning)
+		if err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Wrote a %d byte checkpoint to %s", size, opts.Checkpoint))
+		if opts.LeaveRunning {
+			if err := expectState(conn, r.ID, "running", out); err != nil {
+				return fmt.Errorf("after --checkpoint-leave-running: %w", err)
+			}
+		}
+	}
+	if opts.Restore != "" && opts.RestoreName != "" {
+		// Migration-style: a second container, from the archive alone
+		logger.Info(fmt.Sprintf("Restoring %s from %s as a new container...", opts.RestoreName, opts.Restore))
+		id, err := restoreContainer(conn, r.ID, opts.Restore, opts.RestoreName)
+		if err != nil {
+			return err
+		}
+		trace.produced("container", id)
+		cleanup.push("restored container", "restored container "+id, func() error {
+			return removeContainer(conn, id)
+		})
+		logger.Debug("containers.Wait", "id", id, "condition", define.ContainerStateRunning)
+		err = withContext(ctx, func() error {
+			running := define.ContainerStateRunning
+			_, err := containers.Wait(conn, id, &running)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("waiting for restored container %s to be running: %w", opts.RestoreName, err)
+		}
+		logger.Info(fmt.Sprintf("Restored container %s (%.12s) is running", opts.RestoreName, id))
+	} else if opts.Restore != "" {
+		logger.Info(fmt.Sprintf("Restoring the container from %s...", opts.Restore))
+		id, err := restoreContainer(conn, r.ID, opts.Restore, "")
+		if err != nil {
+			return err
+		}
+		if err := expectState(conn, id, "running", out); err != nil {
+			return err
+		}
+	}
+
+	// List containers: by default only the most recently created one
+	var last *int
+	if opts.ListLimit > 0 {
+		last = &opts.ListLimit
+	}
+	logger.Debug("containers.List", "filters", containerFilters, "all", opts.All, "last", opts.ListLimit)
+	containerList, err := containers.List(conn, containerFilters, &opts.All, last, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+	switch {
+	case containerFormat != nil:
+		if err := printContainersFormatted(out, containerFormat, containerList); err != nil {
+			return err
+		}
+	case opts.All || opts.ListLimit != 1 || containerFilters != nil:
+		printContainerTable(out, containerList)
+	case len(containerList) > 0:
+		logger.Info(fmt.Sprintf("Latest container is %s", containerList[0].Names[0]))
+		if labels := formatLabels(containerList[0].Labels); labels != "" {
+			logger.Info(fmt.Sprintf("Its labels are %s", labels))
+		}
+	}
+
+	// List networks and volumes, which include any the run created
+	if opts.ListNetworks {
+		logger.Debug("network.List")
+		networks, err := network.List(conn)
+		if err != nil {
+			return fmt.Errorf("listing networks: %w", err)
+		}
+		printNetworkTable(out, networks)
+	}
+	if opts.ListVolumes {
+		logger.Debug("volumes.List")
+		vols, err := volumes.List(conn, nil)
+		if err != nil {
+			return fmt.Errorf("listing volumes: %w", err)
+		}
+		printVolumeTable(out, vols)
+	}
+
+	// Show how much space everything takes, like podman system df
+	var usage []diskUsage
+	if opts.DiskUsage {
+		logger.Debug("system.DiskUsage")
+		report, err := system.DiskUsage(conn)
+		if err != nil {
+			return fmt.Errorf("getting disk usage: %w", err)
+		}
+		usage = summarizeDiskUsage(report)
+		printDiskUsage(out, usage)
+	}
+
+	// Container inspect
+	logger.Debug("containers.Inspect", "id", r.ID)
+	ctrData, err := containers.Inspect(conn, r.ID, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting container %s: %w", r.ID, err)
+	}
+	printContainer(out, ctrData, opts)
+	if opts.InspectOut != "" {
+		if err := writeInspect(opts.InspectOut, ctrData, opts.Force); err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Wrote the full inspect data to %s", opts.InspectOut))
+	}
+	if len(opts.NetworkAliases) > 0 {
+		if err := printNetworkAliases(conn, r.ID, opts.Network, out); err != nil {
+			return err
+		}
+	}
+
+	// Container stop, or kill with --kill
+	endStop := timed.start("stop")
+	if opts.Kill {
+		logger.Info(fmt.Sprintf("Killing the container with %s...", opts.KillSignal))
+		logger.Debug("containers.Kill", "id", r.ID, "signal", int(opts.KillSignal))
+		if err := killContainer(conn, r.ID, opts.KillSignal, out); err != nil {
+			return err
+		}
+	} else {
+		logger.Info("Stopping the container...")
+		timeout := uint(opts.StopTimeout)
+		logger.Debug("containers.Stop", "id", r.ID, "timeout", timeout)
+		err = containers.Stop(conn, r.ID, &timeout)
+		if err != nil {
+			return fmt.Errorf("stopping container %s: %w", r.ID, err)
+		}
+	}
+	endStop()
+	if len(replicas) > 0 {
+		logger.Info("Stopping the replicas...")
+		if err := stopReplicas(conn, replicas, uint(opts.StopTimeout), limit); err != nil {
+			return err
+		}
+		if err := printReplicas(out, replicas); err != nil {
+			return err
+		}
+	}
+	if err := <-logsErr; err != nil {
+		return fmt.Errorf("streaming logs of container %s: %w", r.ID, err)
+	}
+
+	ctrData, err = containers.Inspect(conn, r.ID, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting container %s: %w", r.ID, err)
+	}
+	logger.Info(fmt.Sprintf("Container running status is now %s", ctrData.State.Status))
+
+	if s.Pod != "" {
+		if err := printPod(conn, s.Pod, out); err != nil {
+			return err
+		}
+	}
+
+	return finish(ctrData.ImageName, ctrData.State.Status, usage)
+}
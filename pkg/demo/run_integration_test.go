@@ -0,0 +1,71 @@
+//go:build integration
+// +build integration
+
+package demo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/containers/libpod/v2/libpod/define"
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+)
+
+// testImage is small and has a sleep command to keep the container running.
+const testImage = "quay.io/libpod/alpine:latest"
+
+// TestRun runs the whole tutorial against a Podman service, found the same
+// way as the CLI does or given by PODMAN_SOCKET, e.g. one started with
+// `podman system service --time=0`.  Run with: go test -tags integration
+func TestRun(t *testing.T) {
+	socket, identity, err := ResolveSocket(os.Getenv("PODMAN_SOCKET"))
+	if err != nil {
+		t.Skip(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	name := fmt.Sprintf("bindings-sample-test-%d", time.Now().UnixNano())
+	opts := Options{
+		Image:          testImage,
+		Socket:         socket,
+		Command:        []string{"sleep", "60"},
+		Output:         "text",
+		ConnectRetries: 1,
+		ConnectTimeout: 10 * time.Second,
+		WaitCondition:  define.ContainerStateRunning,
+		Name:           name,
+		ListLimit:      1,
+		PullBackoff:    time.Second,
+		StopTimeout:    1,
+	}
+
+	conn, err := Connect(ctx, socket, identity, nil, 1, 10*time.Second, os.Stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := removeContainer(conn, name); err != nil {
+			t.Error(err)
+		}
+		if err := removeImage(conn, testImage, os.Stderr); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Run only returns once the container was running and has been stopped
+	if err := Run(ctx, opts); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	data, err := containers.Inspect(conn, name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.State.Status != "exited" && data.State.Status != "stopped" {
+		t.Errorf("container is %s after Run, expected it to be stopped", data.State.Status)
+	}
+}
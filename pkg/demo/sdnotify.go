@@ -0,0 +1,32 @@
+package demo
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// sdNotify sends state, such as READY=1, to the service manager over the
+// socket in $NOTIFY_SOCKET, as sd_notify(3) does.  When the variable is
+// not set, because the tutorial is not running as a Type=notify unit, it
+// does nothing and returns false.
+func sdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("connecting to the notify socket: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("sending %s to the notify socket: %w", state, err)
+	}
+	return true, nil
+}
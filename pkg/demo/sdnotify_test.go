@@ -0,0 +1,41 @@
+package demo
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSdNotify(t *testing.T) {
+	defer os.Setenv("NOTIFY_SOCKET", os.Getenv("NOTIFY_SOCKET"))
+
+	os.Unsetenv("NOTIFY_SOCKET")
+	if sent, err := sdNotify("READY=1"); sent || err != nil {
+		t.Errorf("without NOTIFY_SOCKET: sdNotify = %v, %v; want false, nil", sent, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	os.Setenv("NOTIFY_SOCKET", path)
+	if sent, err := sdNotify("READY=1"); !sent || err != nil {
+		t.Fatalf("sdNotify = %v, %v; want true, nil", sent, err)
+	}
+	buf := make([]byte, 64)
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "READY=1" {
+		t.Errorf("received %q, want READY=1", got)
+	}
+
+	os.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "missing"))
+	if _, err := sdNotify("READY=1"); err == nil {
+		t.Error("sdNotify to a missing socket succeeded, expected an error")
+	}
+}
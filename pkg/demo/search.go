@@ -0,0 +1,104 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/containers/libpod/v2/pkg/bindings/images"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+)
+
+// searchFilter is a parsed --search-filter: the least number of stars,
+// and whether the image must, or must not, be official or built
+// automatically.
+type searchFilter struct {
+	stars               int
+	official, automated *bool
+}
+
+// parseSearchFilters checks the --search-filter entries, which take the
+// same keys as podman search --filter: stars=N, is-official=BOOL and
+// is-automated=BOOL.
+func parseSearchFilters(entries []string) (searchFilter, error) {
+	var f searchFilter
+	for _, e := range entries {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 {
+			return f, fmt.Errorf("invalid --search-filter %q: expected KEY=VALUE", e)
+		}
+		var err error
+		switch kv[0] {
+		case "stars":
+			if f.stars, err = strconv.Atoi(kv[1]); err != nil || f.stars < 0 {
+				return f, fmt.Errorf("invalid --search-filter %q: stars must be a non-negative number", e)
+			}
+		case "is-official", "is-automated":
+			b, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return f, fmt.Errorf("invalid --search-filter %q: %s must be true or false", e, kv[0])
+			}
+			if kv[0] == "is-official" {
+				f.official = &b
+			} else {
+				f.automated = &b
+			}
+		default:
+			return f, fmt.Errorf("invalid --search-filter %q: the keys are stars, is-official and is-automated", e)
+		}
+	}
+	return f, nil
+}
+
+// match reports whether a search result passes the filter.  The registry
+// marks official and automated images with "[OK]".
+func (f searchFilter) match(r entities.ImageSearchReport) bool {
+	if r.Stars < f.stars {
+		return false
+	}
+	if f.official != nil && (r.Official == "[OK]") != *f.official {
+		return false
+	}
+	if f.automated != nil && (r.Automated == "[OK]") != *f.automated {
+		return false
+	}
+	return true
+}
+
+// searchImages searches the registries the service is configured with
+// for term and prints at most limit results per registry in a table.
+// filter is the parsed form of the filterEntries.
+//
+// images.Search sends every filter under the same query parameter, so
+// only the last one reaches the service; the results are filtered here
+// as well, and so may number fewer than limit.
+func searchImages(conn context.Context, term string, limit int, filterEntries []string, filter searchFilter, out io.Writer) error {
+	reports, err := images.Search(conn, term, entities.ImageSearchOptions{Limit: limit, Filters: filterEntries})
+	if err != nil {
+		return fmt.Errorf("searching for %s: %w", term, err)
+	}
+	var matches []entities.ImageSearchReport
+	for _, r := range reports {
+		if filter.match(r) {
+			matches = append(matches, r)
+		}
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(out, "No images found for %q\n", term)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tNAME\tDESCRIPTION\tSTARS\tOFFICIAL\tAUTOMATED")
+	for _, r := range matches {
+		description := strings.Join(strings.Fields(r.Description), " ")
+		if d := []rune(description); len(d) > 44 {
+			description = string(d[:43]) + "…"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", r.Index, r.Name, description, r.Stars, r.Official, r.Automated)
+	}
+	return w.Flush()
+}
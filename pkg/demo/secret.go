@@ -0,0 +1,213 @@
+package demo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/containers/libpod/v2/pkg/bindings"
+)
+
+// envName matches the environment variable names a shell can test, which
+// checkSecretEnv relies on.
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseSecretEnv converts --secret-env entries, SECRET=ENVVAR, into the
+// secret each variable is set from, keyed by variable.  This version of
+// the SpecGenerator has no field for it, so createWithSpec adds it to the
+// spec as the secret_env field of Podman 4.0 and later.
+func parseSecretEnv(entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	vars := make(map[string]string, len(entries))
+	for _, e := range entries {
+		parts := strings.SplitN(e, "=", 2)
+		switch {
+		case len(parts) != 2 || parts[0] == "":
+			return nil, fmt.Errorf("invalid --secret-env %q: expected SECRET=ENVVAR", e)
+		case !envName.MatchString(parts[1]):
+			return nil, fmt.Errorf("invalid --secret-env %q: %q is not a valid environment variable name", e, parts[1])
+		}
+		if other, ok := vars[parts[1]]; ok {
+			return nil, fmt.Errorf("invalid --secret-env %q: $%s is already set from secret %s", e, parts[1], other)
+		}
+		vars[parts[1]] = parts[0]
+	}
+	return vars, nil
+}
+
+// newSecret is a secret for the run to create, from --create-secret.
+type newSecret struct {
+	name string
+	data []byte
+}
+
+// readCreateSecrets reads the file of each --create-secret entry,
+// NAME=FILE, before anything is created.
+func readCreateSecrets(entries []string) ([]newSecret, error) {
+	var secrets []newSecret
+	for _, e := range entries {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid --create-secret %q: expected NAME=FILE", e)
+		}
+		for _, s := range secrets {
+			if s.name == parts[0] {
+				return nil, fmt.Errorf("invalid --create-secret %q: secret %s is given twice", e, parts[0])
+			}
+		}
+		data, err := ioutil.ReadFile(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid --create-secret %q: %w", e, err)
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("invalid --create-secret %q: %s is empty", e, parts[1])
+		}
+		secrets = append(secrets, newSecret{name: parts[0], data: data})
+	}
+	return secrets, nil
+}
+
+// checkSecrets makes sure that each secret --secret and --secret-env
+// refer to exists, or is one --create-secret creates, so that a typo is
+// reported as such rather than as a create failure.
+func checkSecrets(conn context.Context, opts Options) error {
+	vars, err := parseSecretEnv(opts.SecretEnv)
+	if err != nil {
+		return err
+	}
+	type use struct{ flag, secret string }
+	var uses []use
+	for _, name := range opts.Secrets {
+		uses = append(uses, use{"--secret " + name, name})
+	}
+	for _, name := range sortedVars(vars) {
+		uses = append(uses, use{"--secret-env " + vars[name] + "=" + name, vars[name]})
+	}
+	if len(uses) == 0 {
+		return nil
+	}
+	created := make(map[string]bool)
+	for _, e := range opts.CreateSecrets {
+		created[strings.SplitN(e, "=", 2)[0]] = true
+	}
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return err
+	}
+	for _, u := range uses {
+		if created[u.secret] {
+			continue
+		}
+		response, err := client.DoRequest(nil, http.MethodGet, "/secrets/%s/json", nil, nil, u.secret)
+		if err != nil {
+			return fmt.Errorf("inspecting secret %s: %w", u.secret, err)
+		}
+		if err := response.Process(nil); err != nil {
+			if isNotFound(err) {
+				return fmt.Errorf("invalid %s: no secret named %s; create it with podman secret create or --create-secret", u.flag, u.secret)
+			}
+			return fmt.Errorf("inspecting secret %s: %w", u.secret, err)
+		}
+	}
+	return nil
+}
+
+// createSecret creates a secret called name holding data, with the
+// service's default file driver, and returns its ID.
+func createSecret(conn context.Context, name string, data []byte) (string, error) {
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	params.Set("name", name)
+	response, err := client.DoRequest(bytes.NewReader(data), http.MethodPost, "/secrets/create", params, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating secret %s: %w", name, err)
+	}
+	var report struct{ ID string }
+	if err := response.Process(&report); err != nil {
+		return "", fmt.Errorf("creating secret %s: %w", name, err)
+	}
+	return report.ID, nil
+}
+
+// removeSecret removes the secret called name, treating one that is
+// already gone as success.
+func removeSecret(conn context.Context, name string) error {
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return err
+	}
+	response, err := client.DoRequest(nil, http.MethodDelete, "/secrets/%s", nil, nil, name)
+	if err != nil {
+		return fmt.Errorf("removing secret %s: %w", name, err)
+	}
+	if err := response.Process(nil); err != nil && !isNotFound(err) {
+		return fmt.Errorf("removing secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// secretsField returns the secrets field of the spec that mounts the
+// named secrets at /run/secrets/NAME.  Podman 3.1 and 3.2 take a list of
+// names; later releases a list of objects, which can also set the target,
+// owner and mode of each file.
+func secretsField(version string, names []string) interface{} {
+	if version != "" && olderThan(version, "3.3.0") {
+		return names
+	}
+	type secret struct {
+		Source string
+	}
+	secrets := make([]secret, len(names))
+	for i, name := range names {
+		secrets[i] = secret{Source: name}
+	}
+	return secrets
+}
+
+// checkSecretEnv execs a shell in the container to check that each
+// --secret-env variable is set.  Only the shell's exit code is looked at,
+// so the secret itself is never read back or printed.
+func checkSecretEnv(ctx context.Context, conn context.Context, id string, entries []string, out io.Writer) error {
+	vars, err := parseSecretEnv(entries)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedVars(vars) {
+		test := []string{"sh", "-c", fmt.Sprintf(`test "${%s+set}" = set`, name)}
+		code, err := runExec(ctx, conn, id, test, nil, io.Discard, io.Discard)
+		if err != nil {
+			return fmt.Errorf("checking $%s: %w", name, err)
+		}
+		switch code {
+		case 0:
+		case 126, 127:
+			return fmt.Errorf("checking $%s: the container has no sh to run the check with", name)
+		default:
+			return fmt.Errorf("$%s is not set in the container, though --secret-env sets it from secret %s", name, vars[name])
+		}
+		fmt.Fprintf(out, "Secret %s is set in $%s\n", vars[name], name)
+	}
+	return nil
+}
+
+// sortedVars returns the variables of a parseSecretEnv map in order.
+func sortedVars(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,92 @@
+package demo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSecretEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", entries: nil, want: nil},
+		{name: "pairs", entries: []string{"db-pass=DB_PASSWORD", "token=API_TOKEN"}, want: map[string]string{"DB_PASSWORD": "db-pass", "API_TOKEN": "token"}},
+		{name: "one secret twice", entries: []string{"token=A", "token=B"}, want: map[string]string{"A": "token", "B": "token"}},
+		{name: "missing equals", entries: []string{"token"}, wantErr: true},
+		{name: "empty secret", entries: []string{"=TOKEN"}, wantErr: true},
+		{name: "empty variable", entries: []string{"token="}, wantErr: true},
+		{name: "bad variable", entries: []string{"token=1TOKEN"}, wantErr: true},
+		{name: "shell in variable", entries: []string{"token=A}; id; #"}, wantErr: true},
+		{name: "variable twice", entries: []string{"a=TOKEN", "b=TOKEN"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSecretEnv(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSecretEnv(%q) error = %v, wantErr %v", tt.entries, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSecretEnv(%q) = %v, want %v", tt.entries, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCreateSecrets(t *testing.T) {
+	dir := t.TempDir()
+	token := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(token, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := ioutil.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readCreateSecrets([]string{"api-token=" + token, "copy=" + token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []newSecret{{name: "api-token", data: []byte("s3cret\n")}, {name: "copy", data: []byte("s3cret\n")}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readCreateSecrets = %+v, want %+v", got, want)
+	}
+
+	for _, entries := range [][]string{
+		{"api-token"},
+		{"=" + token},
+		{"api-token="},
+		{"api-token=" + filepath.Join(dir, "missing")},
+		{"api-token=" + empty},
+		{"api-token=" + token, "api-token=" + token},
+	} {
+		if _, err := readCreateSecrets(entries); err == nil {
+			t.Errorf("readCreateSecrets(%q) succeeded, want an error", entries)
+		}
+	}
+}
+
+func TestSecretsField(t *testing.T) {
+	tests := []struct{ version, want string }{
+		{"3.1.2", `["db","token"]`},
+		{"3.2.3", `["db","token"]`},
+		{"3.3.0", `[{"Source":"db"},{"Source":"token"}]`},
+		{"4.9.4", `[{"Source":"db"},{"Source":"token"}]`},
+		{"", `[{"Source":"db"},{"Source":"token"}]`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(secretsField(tt.version, []string{"db", "token"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("secretsField(%q) = %s, want %s", tt.version, data, tt.want)
+		}
+	}
+}
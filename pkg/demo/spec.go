@@ -0,0 +1,1029 @@
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/libpod/v2/pkg/specgen"
+	"github.com/containers/storage"
+	"github.com/containers/storage/pkg/idtools"
+	"github.com/lsm5/bindings-sample/internal/shlex"
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// buildSpec turns the parsed options into the SpecGenerator sent to
+// containers.CreateWithSpec.  Malformed option values are reported here,
+// before anything is pulled or created.
+func buildSpec(opts Options) (*specgen.SpecGenerator, error) {
+	s := specgen.NewSpecGenerator(opts.Image, false)
+	if opts.Rootfs != "" {
+		rootfs, err := checkRootfs(opts.Rootfs)
+		if err != nil {
+			return nil, err
+		}
+		s = specgen.NewSpecGenerator(rootfs, true)
+	}
+	s.Name = opts.Name
+	// Attaching is interactive, so keep the container's stdin open.  When
+	// our stdin is piped, the container gets no terminal, so that where
+	// the input ends it reads end-of-file rather than waiting for a ^D.
+	s.Terminal = !(opts.Attach && stdinPiped())
+	s.Stdin = opts.Attach
+
+	s.Init = opts.Init
+	s.InitPath = opts.InitPath
+
+	// The process is the entrypoint followed by the command, which acts
+	// as the entrypoint's arguments.  Setting only the entrypoint drops
+	// the image's command; setting only the command keeps the image's
+	// entrypoint.  Leaving both empty runs the image's defaults.
+	//
+	// A nil entrypoint is "not set", so the image's is used; an empty one
+	// clears it, so the command, or failing that the image's command, runs
+	// on its own.  --no-entrypoint, or --entrypoint "", asks for that.
+	if opts.NoEntrypoint {
+		s.Entrypoint = []string{}
+	} else if opts.Entrypoint != "" && opts.ShellParse {
+		entrypoint, err := shlex.Split(opts.Entrypoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --entrypoint %q: %w", opts.Entrypoint, err)
+		}
+		if len(entrypoint) == 0 {
+			return nil, fmt.Errorf("invalid --entrypoint %q: no arguments", opts.Entrypoint)
+		}
+		s.Entrypoint = entrypoint
+	} else if opts.Entrypoint != "" {
+		entrypoint, err := parseEntrypoint(opts.Entrypoint)
+		if err != nil {
+			return nil, err
+		}
+		s.Entrypoint = entrypoint
+	}
+	// With --shell-parse, each --cmd is a command line of its own to split
+	if len(opts.Command) > 0 && opts.ShellParse {
+		for _, c := range opts.Command {
+			args, err := shlex.Split(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --cmd %q: %w", c, err)
+			}
+			s.Command = append(s.Command, args...)
+		}
+		if len(s.Command) == 0 {
+			return nil, fmt.Errorf("invalid --cmd: no arguments")
+		}
+	} else if len(opts.Command) > 0 {
+		s.Command = opts.Command
+	}
+
+	// Values from --env-file and --label-file are overridden by --env and
+	// --label, and those inherited with --env-host by all of them
+	env, err := readKeyValueFiles("--env-file", opts.EnvFiles)
+	if err != nil {
+		return nil, err
+	}
+	flagEnv, err := parseEnv(opts.Env)
+	if err != nil {
+		return nil, err
+	}
+	// Secrets have no place in the spec; see specExtras
+	for i, name := range opts.Secrets {
+		if name == "" {
+			return nil, fmt.Errorf("invalid --secret: empty secret name")
+		}
+		if hasOption(opts.Secrets[:i], name) {
+			return nil, fmt.Errorf("invalid --secret %q: given twice", name)
+		}
+	}
+	if _, err := parseSecretEnv(opts.SecretEnv); err != nil {
+		return nil, err
+	}
+	for k, v := range flagEnv {
+		env[k] = v
+	}
+	if opts.EnvHost {
+		for k, v := range hostEnv() {
+			if _, ok := env[k]; !ok {
+				env[k] = v
+			}
+		}
+	}
+	s.Env = env
+
+	labels, err := readKeyValueFiles("--label-file", opts.LabelFiles)
+	if err != nil {
+		return nil, err
+	}
+	flagLabels, err := parseLabels(opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range flagLabels {
+		labels[k] = v
+	}
+	s.Labels = labels
+	if len(opts.Annotations) > 0 {
+		if s.Annotations, err = parseAnnotations(opts.Annotations); err != nil {
+			return nil, err
+		}
+	}
+
+	mounts, volumes, err := parseVolumes(opts.Volumes)
+	if err != nil {
+		return nil, err
+	}
+	s.Mounts = mounts
+	s.Volumes = volumes
+	tmpfs, err := parseTmpfs(opts.Tmpfs)
+	if err != nil {
+		return nil, err
+	}
+	s.Mounts = append(s.Mounts, tmpfs...)
+	// Image mounts have no place in the spec; see specExtras
+	typedMounts, typedVolumes, _, err := parseMountTypes(opts.MountTypes)
+	if err != nil {
+		return nil, err
+	}
+	s.Mounts = append(s.Mounts, typedMounts...)
+	s.Volumes = append(s.Volumes, typedVolumes...)
+	s.ReadOnlyFilesystem = opts.ReadOnly
+	// The bindings have no read-only tmpfs setting, so add the mounts
+	// that Podman would add itself, unless something is already mounted
+	// there
+	if opts.ReadOnly && opts.ReadOnlyTmpfs {
+		var paths []string
+	next:
+		for _, path := range []string{"/run", "/tmp", "/var/tmp"} {
+			for _, m := range s.Mounts {
+				if m.Destination == path {
+					continue next
+				}
+			}
+			paths = append(paths, path)
+		}
+		rwTmpfs, err := parseTmpfs(paths)
+		if err != nil {
+			return nil, err
+		}
+		s.Mounts = append(s.Mounts, rwTmpfs...)
+	}
+	// --create-volume creates the volume before the container
+	if opts.CreateVolume != "" {
+		s.Volumes = append(s.Volumes, &specgen.NamedVolume{
+			Name: opts.CreateVolume,
+			Dest: "/mnt/" + opts.CreateVolume,
+		})
+	}
+
+	ports, err := parsePortMappings(opts.Publish)
+	if err != nil {
+		return nil, err
+	}
+	s.PortMappings = ports
+
+	s.Hostname = opts.Hostname
+	for _, d := range opts.DNS {
+		ip := net.ParseIP(d)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid --dns %q: not an IP address", d)
+		}
+		s.DNSServers = append(s.DNSServers, ip)
+	}
+	s.DNSSearch = opts.DNSSearch
+	for _, h := range opts.AddHosts {
+		// The IP may be IPv6, so split at the first colon only
+		hostIP := strings.SplitN(h, ":", 2)
+		if len(hostIP) != 2 || hostIP[0] == "" || net.ParseIP(hostIP[1]) == nil {
+			return nil, fmt.Errorf("invalid --add-host %q: expected HOST:IP", h)
+		}
+		s.HostAdd = append(s.HostAdd, h)
+	}
+
+	// CNI networks are only joined in bridge mode, which is not the
+	// rootless default
+	if opts.Network != "" {
+		s.NetNS = specgen.Namespace{NSMode: specgen.Bridge}
+		s.CNINetworks = []string{opts.Network}
+	}
+	for _, a := range opts.NetworkAliases {
+		if opts.Network == "" {
+			return nil, fmt.Errorf("--network-alias needs --network; aliases only work on a named network")
+		}
+		if err := validateAlias(a); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ns := range []struct {
+		flagName, value string
+		field           *specgen.Namespace
+	}{
+		{"--cgroupns", opts.CgroupNS, &s.CgroupNS},
+		{"--pidns", opts.PidNS, &s.PidNS},
+		{"--ipcns", opts.IpcNS, &s.IpcNS},
+		{"--utsns", opts.UtsNS, &s.UtsNS},
+	} {
+		if ns.value == "" {
+			continue
+		}
+		if *ns.field, err = parseNamespace(ns.flagName, ns.value); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.UserNS != "" {
+		if s.UserNS, err = parseUserNS(opts.UserNS); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.UIDMaps) > 0 || len(opts.GIDMaps) > 0 {
+		if s.IDMappings, err = parseIDMappings(opts.UIDMaps, opts.GIDMaps); err != nil {
+			return nil, err
+		}
+		s.UserNS = specgen.Namespace{NSMode: specgen.Private}
+	}
+
+	if s.Sysctl, err = parseSysctls(opts.Sysctls); err != nil {
+		return nil, err
+	}
+	if s.Rlimits, err = parseUlimits(opts.Ulimits); err != nil {
+		return nil, err
+	}
+	if opts.StopSignal != 0 {
+		sig := opts.StopSignal
+		s.StopSignal = &sig
+	}
+	if s.Devices, err = parseDevices(opts.Devices); err != nil {
+		return nil, err
+	}
+
+	s.Privileged = opts.Privileged
+	if opts.SeccompProfile != "" {
+		if s.SeccompProfilePath, err = checkSeccompProfile(opts.SeccompProfile); err != nil {
+			return nil, err
+		}
+	}
+	s.ApparmorProfile = opts.Apparmor
+	if s.CapAdd, err = parseCapabilities("--cap-add", opts.CapAdd); err != nil {
+		return nil, err
+	}
+	if s.CapDrop, err = parseCapabilities("--cap-drop", opts.CapDrop); err != nil {
+		return nil, err
+	}
+
+	if err := validateUser(opts.User); err != nil {
+		return nil, err
+	}
+	s.User = opts.User
+	for _, g := range opts.GroupAdd {
+		if err := validateGroup(g); err != nil {
+			return nil, err
+		}
+	}
+	s.Groups = opts.GroupAdd
+	if opts.Workdir != "" && !filepath.IsAbs(opts.Workdir) {
+		return nil, fmt.Errorf("invalid --workdir %q: must be an absolute path", opts.Workdir)
+	}
+	s.WorkDir = opts.Workdir
+	// The SpecGenerator has no umask setting in these bindings, so a shell
+	// sets it and then execs the process.  The image's own entrypoint and
+	// command are not known here, so the process has to be given.
+	if opts.Umask != "" {
+		umask, err := parseUmask(opts.Umask)
+		if err != nil {
+			return nil, err
+		}
+		if len(s.Entrypoint) == 0 && len(s.Command) == 0 {
+			return nil, fmt.Errorf("--umask needs --entrypoint or a command to run after setting it")
+		}
+		process := append(append([]string{}, s.Entrypoint...), s.Command...)
+		s.Entrypoint = append([]string{"/bin/sh", "-c", "umask " + umask + ` && exec "$@"`, "sh"}, process...)
+		s.Command = nil
+	}
+	if opts.OOMScoreAdj != 0 {
+		adj := opts.OOMScoreAdj
+		s.OOMScoreAdj = &adj
+	}
+	// The SpecGenerator has no timezone setting in these bindings either,
+	// so the zone file is bound over /etc/localtime, and TZ is set for
+	// programs that look there first.  An explicit TZ in --env wins.
+	if opts.TZ != "" {
+		zone, err := checkTimezone(opts.TZ)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range s.Mounts {
+			if m.Destination == "/etc/localtime" {
+				return nil, fmt.Errorf("--tz cannot be combined with a mount at /etc/localtime")
+			}
+		}
+		s.Mounts = append(s.Mounts, spec.Mount{Type: "bind", Source: zone, Destination: "/etc/localtime", Options: []string{"ro"}})
+		if _, ok := s.Env["TZ"]; !ok {
+			s.Env["TZ"] = opts.TZ
+		}
+	}
+
+	if opts.Memory != "" || opts.CPUs > 0 {
+		s.ResourceLimits = &spec.LinuxResources{}
+	}
+	if opts.Memory != "" {
+		limit, err := parseSize(opts.Memory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --memory: %w", err)
+		}
+		s.ResourceLimits.Memory = &spec.LinuxMemory{Limit: &limit}
+	}
+	if opts.CPUs > 0 {
+		// --cpus is shorthand for a CFS quota over the default period
+		period := uint64(cpuPeriod)
+		quota := int64(opts.CPUs * cpuPeriod)
+		s.ResourceLimits.CPU = &spec.LinuxCPU{Period: &period, Quota: &quota}
+	}
+
+	if opts.ShmSize != "" {
+		size, err := parseSize(opts.ShmSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --shm-size: %w", err)
+		}
+		if !s.IpcNS.IsDefault() && !s.IpcNS.IsPrivate() {
+			return nil, fmt.Errorf("--shm-size needs a private IPC namespace, not --ipcns %s", opts.IpcNS)
+		}
+		s.ShmSize = &size
+	}
+
+	if opts.HealthCmd != "" {
+		s.HealthConfig = &manifest.Schema2HealthConfig{
+			Test:     []string{"CMD-SHELL", opts.HealthCmd},
+			Interval: opts.HealthInterval,
+			Retries:  opts.HealthRetries,
+		}
+	}
+
+	switch opts.Restart {
+	case "", "no", "on-failure", "always", "unless-stopped":
+		s.RestartPolicy = opts.Restart
+	default:
+		return nil, fmt.Errorf("invalid --restart %q: must be no, on-failure, always or unless-stopped", opts.Restart)
+	}
+	if opts.AutoRemove && opts.Restart != "" && opts.Restart != "no" {
+		return nil, fmt.Errorf("--auto-remove cannot be combined with --restart=%s", opts.Restart)
+	}
+	s.Remove = opts.AutoRemove
+	if opts.RestartRetries > 0 {
+		if opts.Restart != "on-failure" {
+			return nil, fmt.Errorf("--restart-retries can only be used with --restart=on-failure")
+		}
+		retries := opts.RestartRetries
+		s.RestartRetries = &retries
+	}
+
+	// The flags override the --spec file
+	if opts.SpecFile != "" {
+		base, err := loadSpec(opts.SpecFile)
+		if err != nil {
+			return nil, err
+		}
+		s = mergeSpec(base, s)
+		if opts.Rootfs != "" {
+			s.Image = ""
+		}
+		if s.Image == "" && s.Rootfs == "" {
+			return nil, fmt.Errorf("--spec %s sets no image; add one or pass --image", opts.SpecFile)
+		}
+	}
+
+	return s, nil
+}
+
+// cpuPeriod is the CFS scheduler period, in microseconds, that --cpus is
+// applied over.
+const cpuPeriod = 100000
+
+// parseSize converts a size such as "512", "64k", "128m" or "1g" into
+// bytes.  The suffix is case-insensitive and may be followed by a "b".
+func parseSize(value string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToLower(value), "b")
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			s = s[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("expected a positive size such as 128m, not %q", value)
+	}
+	return n * multiplier, nil
+}
+
+// parseSysctls converts KEY=VALUE pairs into a sysctl map.
+func parseSysctls(entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	sysctls := make(map[string]string, len(entries))
+	for _, e := range entries {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid --sysctl %q: expected KEY=VALUE", e)
+		}
+		sysctls[kv[0]] = kv[1]
+	}
+	return sysctls, nil
+}
+
+// rlimits maps the --ulimit names onto the rlimit types of the OCI spec.
+var rlimits = map[string]string{
+	"as": "RLIMIT_AS", "core": "RLIMIT_CORE", "cpu": "RLIMIT_CPU",
+	"data": "RLIMIT_DATA", "fsize": "RLIMIT_FSIZE", "locks": "RLIMIT_LOCKS",
+	"memlock": "RLIMIT_MEMLOCK", "msgqueue": "RLIMIT_MSGQUEUE", "nice": "RLIMIT_NICE",
+	"nofile": "RLIMIT_NOFILE", "nproc": "RLIMIT_NPROC", "rss": "RLIMIT_RSS",
+	"rtprio": "RLIMIT_RTPRIO", "rttime": "RLIMIT_RTTIME", "sigpending": "RLIMIT_SIGPENDING",
+	"stack": "RLIMIT_STACK",
+}
+
+// parseUlimits converts NAME=SOFT:HARD entries into rlimits.  A single
+// value sets both the soft and the hard limit.
+func parseUlimits(entries []string) ([]spec.POSIXRlimit, error) {
+	var limits []spec.POSIXRlimit
+	for _, e := range entries {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid --ulimit %q: expected NAME=SOFT:HARD", e)
+		}
+		typ, ok := rlimits[strings.ToLower(kv[0])]
+		if !ok {
+			return nil, fmt.Errorf("invalid --ulimit %q: unknown limit %q", e, kv[0])
+		}
+		values := strings.SplitN(kv[1], ":", 2)
+		soft, err := strconv.ParseUint(values[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --ulimit %q: bad soft limit: %w", e, err)
+		}
+		hard := soft
+		if len(values) == 2 {
+			if hard, err = strconv.ParseUint(values[1], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid --ulimit %q: bad hard limit: %w", e, err)
+			}
+		}
+		if soft > hard {
+			return nil, fmt.Errorf("invalid --ulimit %q: soft limit is above the hard limit", e)
+		}
+		limits = append(limits, spec.POSIXRlimit{Type: typ, Soft: soft, Hard: hard})
+	}
+	return limits, nil
+}
+
+// capabilities is the set of Linux capabilities known to the runtime.
+var capabilities = map[string]bool{
+	"CAP_AUDIT_CONTROL": true, "CAP_AUDIT_READ": true, "CAP_AUDIT_WRITE": true,
+	"CAP_BLOCK_SUSPEND": true, "CAP_BPF": true, "CAP_CHECKPOINT_RESTORE": true,
+	"CAP_CHOWN": true, "CAP_DAC_OVERRIDE": true, "CAP_DAC_READ_SEARCH": true,
+	"CAP_FOWNER": true, "CAP_FSETID": true, "CAP_IPC_LOCK": true,
+	"CAP_IPC_OWNER": true, "CAP_KILL": true, "CAP_LEASE": true,
+	"CAP_LINUX_IMMUTABLE": true, "CAP_MAC_ADMIN": true, "CAP_MAC_OVERRIDE": true,
+	"CAP_MKNOD": true, "CAP_NET_ADMIN": true, "CAP_NET_BIND_SERVICE": true,
+	"CAP_NET_BROADCAST": true, "CAP_NET_RAW": true, "CAP_PERFMON": true,
+	"CAP_SETFCAP": true, "CAP_SETGID": true, "CAP_SETPCAP": true,
+	"CAP_SETUID": true, "CAP_SYS_ADMIN": true, "CAP_SYS_BOOT": true,
+	"CAP_SYS_CHROOT": true, "CAP_SYS_MODULE": true, "CAP_SYS_NICE": true,
+	"CAP_SYS_PACCT": true, "CAP_SYS_PTRACE": true, "CAP_SYS_RAWIO": true,
+	"CAP_SYS_RESOURCE": true, "CAP_SYS_TIME": true, "CAP_SYS_TTY_CONFIG": true,
+	"CAP_SYSLOG": true, "CAP_WAKE_ALARM": true,
+}
+
+// parseCapabilities normalizes capability names to their CAP_ form, so
+// NET_ADMIN, net_admin and CAP_NET_ADMIN are all accepted, and rejects
+// names that are not capabilities.  ALL stands for every capability.
+func parseCapabilities(flagName string, names []string) ([]string, error) {
+	var caps []string
+	for _, name := range names {
+		c := strings.ToUpper(name)
+		if c != "ALL" && !strings.HasPrefix(c, "CAP_") {
+			c = "CAP_" + c
+		}
+		if c != "ALL" && !capabilities[c] {
+			return nil, fmt.Errorf("invalid %s %q: unknown capability", flagName, name)
+		}
+		caps = append(caps, c)
+	}
+	return caps, nil
+}
+
+// checkRootfs makes sure that a --rootfs directory has something in it,
+// and returns its absolute path.  Like a --seccomp-profile, the directory
+// has to be at the same path on the service's host.
+func checkRootfs(dir string) (string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("reading --rootfs: %w", err)
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("invalid --rootfs %s: the directory is empty", dir)
+	}
+	return filepath.Abs(dir)
+}
+
+// checkSeccompProfile makes sure that a --seccomp-profile file holds a
+// seccomp profile, and returns its absolute path.  The service reads the
+// file itself, so it has to be at the same path on the service's host.
+// "unconfined" turns seccomp off and is passed on as-is.
+func checkSeccompProfile(path string) (string, error) {
+	if path == "unconfined" {
+		return path, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading --seccomp-profile: %w", err)
+	}
+	var profile struct {
+		DefaultAction string `json:"defaultAction"`
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return "", fmt.Errorf("invalid --seccomp-profile %s: %w", path, err)
+	}
+	if profile.DefaultAction == "" {
+		return "", fmt.Errorf("invalid --seccomp-profile %s: no defaultAction; is it a seccomp profile?", path)
+	}
+	return filepath.Abs(path)
+}
+
+// validateUser accepts an empty user, a numeric uid, a numeric uid:gid
+// pair or a user name.  Names are resolved inside the container, so they
+// cannot be checked any further here.
+func validateUser(user string) error {
+	if user == "" || isNumeric(user) {
+		return nil
+	}
+	if i := strings.Index(user, ":"); i >= 0 {
+		if !isNumeric(user[:i]) || !isNumeric(user[i+1:]) {
+			return fmt.Errorf("invalid --user %q: expected uid, uid:gid or name", user)
+		}
+		return nil
+	}
+	for _, r := range user {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_.-", r)) {
+			return fmt.Errorf("invalid --user %q: expected uid, uid:gid or name", user)
+		}
+	}
+	return nil
+}
+
+// validateGroup accepts a numeric gid or a group name, which like a user
+// name is resolved inside the container.
+func validateGroup(group string) error {
+	if isNumeric(group) {
+		return nil
+	}
+	if group == "" || strings.Trim(group, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_.-") != "" {
+		return fmt.Errorf("invalid --group-add %q: expected gid or name", group)
+	}
+	return nil
+}
+
+// validateAlias checks that a --network-alias is a DNS name: dot-separated
+// labels of letters, digits and hyphens, none starting or ending with a
+// hyphen.
+func validateAlias(alias string) error {
+	if len(alias) > 253 {
+		return fmt.Errorf("invalid --network-alias %q: longer than 253 characters", alias)
+	}
+	for _, label := range strings.Split(alias, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' ||
+			strings.Trim(label, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-") != "" {
+			return fmt.Errorf("invalid --network-alias %q: expected a DNS name such as db or web-1.internal", alias)
+		}
+	}
+	return nil
+}
+
+// isNumeric reports whether s is a non-negative decimal number.
+func isNumeric(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
+
+// zoneinfoDir is where the system keeps its timezone database.
+const zoneinfoDir = "/usr/share/zoneinfo"
+
+// checkTimezone checks that a --tz is the name of a zone in the system's
+// timezone database, such as Europe/Prague, and returns the path of its
+// file.  The file is bound into the container by the service, so this
+// assumes the service runs on this host, or on one with the same zones.
+func checkTimezone(name string) (string, error) {
+	path := filepath.Join(zoneinfoDir, name)
+	if filepath.IsAbs(name) || !strings.HasPrefix(path, zoneinfoDir+"/") {
+		return "", fmt.Errorf("invalid --tz %q: expected a zone name such as Europe/Prague", name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return "", fmt.Errorf("invalid --tz %q: %v", name, err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.IsDir() {
+		return "", fmt.Errorf("invalid --tz %q: no such zone in %s", name, zoneinfoDir)
+	}
+	return path, nil
+}
+
+// parseUmask checks that a --umask is an octal file mode mask, such as
+// "022" or "0077", and returns it with four digits.
+func parseUmask(value string) (string, error) {
+	mask, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mask > 0777 {
+		return "", fmt.Errorf("invalid --umask %q: expected an octal mask such as 0022", value)
+	}
+	return fmt.Sprintf("%04o", mask), nil
+}
+
+// parseEntrypoint accepts either a JSON array of arguments or a single
+// command, which is used as-is without any shell splitting.
+func parseEntrypoint(value string) ([]string, error) {
+	if !strings.HasPrefix(strings.TrimSpace(value), "[") {
+		return []string{value}, nil
+	}
+	var entrypoint []string
+	if err := json.Unmarshal([]byte(value), &entrypoint); err != nil {
+		return nil, fmt.Errorf("invalid --entrypoint %q: %w", value, err)
+	}
+	if len(entrypoint) == 0 {
+		return nil, fmt.Errorf("invalid --entrypoint %q: empty array", value)
+	}
+	return entrypoint, nil
+}
+
+// hostEnv returns the environment of this program, for --env-host.
+//
+// Everything is copied, including tokens, passwords and cloud credentials
+// that happen to be exported in the calling shell.  They end up in the
+// container's config, where anyone who can inspect the container, or run
+// as its user, can see them, and in any image committed from it.  Only use
+// --env-host with containers that are trusted with all of them.
+func hostEnv() map[string]string {
+	env := make(map[string]string)
+	for _, e := range os.Environ() {
+		if kv := strings.SplitN(e, "=", 2); len(kv) == 2 && kv[0] != "" {
+			env[kv[0]] = kv[1]
+		}
+	}
+	return env
+}
+
+// parseEnv converts KEY=VALUE pairs into an environment map.
+func parseEnv(entries []string) (map[string]string, error) {
+	env := make(map[string]string, len(entries))
+	for _, e := range entries {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid --env %q: expected KEY=VALUE", e)
+		}
+		env[kv[0]] = kv[1]
+	}
+	return env, nil
+}
+
+// parseLabels converts KEY=VALUE pairs into a label map.  Giving the same
+// key twice is almost certainly a mistake, so it is rejected rather than
+// letting the last value win.
+func parseLabels(entries []string) (map[string]string, error) {
+	return parseKeyValues("--label", "label", entries)
+}
+
+// parseAnnotations converts KEY=VALUE pairs into OCI annotations, with the
+// same rules as parseLabels.
+func parseAnnotations(entries []string) (map[string]string, error) {
+	return parseKeyValues("--annotation", "annotation", entries)
+}
+
+func parseKeyValues(flagName, what string, entries []string) (map[string]string, error) {
+	values := make(map[string]string, len(entries))
+	for _, e := range entries {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid %s %q: expected KEY=VALUE", flagName, e)
+		}
+		if _, ok := values[kv[0]]; ok {
+			return nil, fmt.Errorf("invalid %s %q: %s %s is already set", flagName, e, what, kv[0])
+		}
+		values[kv[0]] = kv[1]
+	}
+	return values, nil
+}
+
+// parseNamespace converts a namespace mode, one of host, private, ns:PATH
+// or container:ID, into a namespace for the spec.
+func parseNamespace(flagName, value string) (specgen.Namespace, error) {
+	switch {
+	case value == "host":
+		return specgen.Namespace{NSMode: specgen.Host}, nil
+	case value == "private":
+		return specgen.Namespace{NSMode: specgen.Private}, nil
+	case strings.HasPrefix(value, "ns:"):
+		path := strings.TrimPrefix(value, "ns:")
+		if !filepath.IsAbs(path) {
+			return specgen.Namespace{}, fmt.Errorf("invalid %s %q: ns: needs an absolute path, such as ns:/proc/1/ns/pid", flagName, value)
+		}
+		return specgen.Namespace{NSMode: specgen.Path, Value: path}, nil
+	case strings.HasPrefix(value, "container:"):
+		id := strings.TrimPrefix(value, "container:")
+		if id == "" {
+			return specgen.Namespace{}, fmt.Errorf("invalid %s %q: container: needs a container name or ID", flagName, value)
+		}
+		return specgen.Namespace{NSMode: specgen.FromContainer, Value: id}, nil
+	}
+	return specgen.Namespace{}, fmt.Errorf("invalid %s %q: expected host, private, ns:PATH or container:ID", flagName, value)
+}
+
+// parseDevices checks HOST[:CONTAINER[:PERMS]] device entries and turns
+// them into spec devices.  The service parses the entry itself, from the
+// device's Path, so it is passed on whole.
+func parseDevices(entries []string) ([]spec.LinuxDevice, error) {
+	var devices []spec.LinuxDevice
+	for _, e := range entries {
+		fields := strings.Split(e, ":")
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("invalid --device %q: expected HOST[:CONTAINER[:PERMS]]", e)
+		}
+		for i, path := range fields {
+			if i < 2 && !filepath.IsAbs(path) {
+				return nil, fmt.Errorf("invalid --device %q: %q is not an absolute path", e, path)
+			}
+		}
+		if len(fields) == 3 && !isDeviceMode(fields[2]) {
+			return nil, fmt.Errorf("invalid --device %q: permissions %q must be a combination of r, w and m", e, fields[2])
+		}
+		devices = append(devices, spec.LinuxDevice{Path: e})
+	}
+	return devices, nil
+}
+
+// isDeviceMode reports whether mode is a non-empty combination of r
+// (read), w (write) and m (mknod), each at most once.
+func isDeviceMode(mode string) bool {
+	if mode == "" {
+		return false
+	}
+	seen := map[rune]bool{}
+	for _, c := range mode {
+		if !strings.ContainsRune("rwm", c) || seen[c] {
+			return false
+		}
+		seen[c] = true
+	}
+	return true
+}
+
+// noMap is podman's nomap user namespace mode, which maps the container to
+// the user's subordinate IDs only.  specgen has no constant for it in this
+// version, and a service that predates it rejects the spec.
+const noMap specgen.NamespaceMode = "nomap"
+
+// parseUserNS converts a --userns mode into a user namespace for the spec.
+func parseUserNS(value string) (specgen.Namespace, error) {
+	switch value {
+	case "host":
+		return specgen.Namespace{NSMode: specgen.Host}, nil
+	case "keep-id":
+		return specgen.Namespace{NSMode: specgen.KeepID}, nil
+	case "auto":
+		return specgen.Namespace{NSMode: specgen.Auto}, nil
+	case "nomap":
+		return specgen.Namespace{NSMode: noMap}, nil
+	}
+	return specgen.Namespace{}, fmt.Errorf("invalid --userns %q: expected host, keep-id, auto or nomap", value)
+}
+
+// parseIDMappings converts --uidmap and --gidmap values of the form
+// CONTAINER_ID:HOST_ID:SIZE into ID mappings.  As with podman, the UID
+// mappings are also used for GIDs if no --gidmap is given.
+func parseIDMappings(uidMaps, gidMaps []string) (*storage.IDMappingOptions, error) {
+	uids, err := parseIDMaps("--uidmap", uidMaps)
+	if err != nil {
+		return nil, err
+	}
+	gids, err := parseIDMaps("--gidmap", gidMaps)
+	if err != nil {
+		return nil, err
+	}
+	if len(gids) == 0 {
+		gids = uids
+	}
+	return &storage.IDMappingOptions{UIDMap: uids, GIDMap: gids}, nil
+}
+
+func parseIDMaps(flagName string, values []string) ([]idtools.IDMap, error) {
+	var maps []idtools.IDMap
+	for _, v := range values {
+		fields := strings.Split(v, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid %s %q: expected CONTAINER_ID:HOST_ID:SIZE", flagName, v)
+		}
+		var ids [3]int
+		for i, f := range fields {
+			n, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %q is not an ID", flagName, v, f)
+			}
+			ids[i] = int(n)
+		}
+		if ids[2] == 0 {
+			return nil, fmt.Errorf("invalid %s %q: the size must be at least 1", flagName, v)
+		}
+		maps = append(maps, idtools.IDMap{ContainerID: ids[0], HostID: ids[1], Size: ids[2]})
+	}
+	return maps, nil
+}
+
+// readKeyValueFiles reads KEY=VALUE lines from each file into one map,
+// skipping blank lines and # comments.  Later lines and files override
+// earlier ones.
+func readKeyValueFiles(flagName string, paths []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", flagName, err)
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return nil, fmt.Errorf("invalid %s entry at %s:%d: expected KEY=VALUE, got %q", flagName, path, i+1, line)
+			}
+			values[kv[0]] = kv[1]
+		}
+	}
+	return values, nil
+}
+
+// parseVolumes converts SOURCE:DEST[:OPTS] entries into mounts.  An
+// absolute SOURCE is bind mounted from the host; a bare name refers to a
+// named volume, which Podman creates on demand.  OPTS is a comma-separated
+// list such as "ro,z".
+func parseVolumes(entries []string) ([]spec.Mount, []*specgen.NamedVolume, error) {
+	var (
+		mounts  []spec.Mount
+		volumes []*specgen.NamedVolume
+	)
+	for _, e := range entries {
+		fields := strings.Split(e, ":")
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, nil, fmt.Errorf("invalid --volume %q: expected SOURCE:DEST[:OPTS]", e)
+		}
+		src, dest := fields[0], fields[1]
+		if src == "" {
+			return nil, nil, fmt.Errorf("invalid --volume %q: empty source", e)
+		}
+		if !filepath.IsAbs(dest) {
+			return nil, nil, fmt.Errorf("invalid --volume %q: container path %q must be absolute", e, dest)
+		}
+		var mountOpts []string
+		if len(fields) == 3 {
+			if fields[2] == "" {
+				return nil, nil, fmt.Errorf("invalid --volume %q: empty options", e)
+			}
+			mountOpts = strings.Split(fields[2], ",")
+		}
+
+		switch {
+		case filepath.IsAbs(src):
+			if !hasOption(mountOpts, "bind") && !hasOption(mountOpts, "rbind") {
+				mountOpts = append(mountOpts, "rbind")
+			}
+			mounts = append(mounts, spec.Mount{
+				Type:        "bind",
+				Source:      src,
+				Destination: dest,
+				Options:     mountOpts,
+			})
+		case strings.ContainsRune(src, '/') || strings.HasPrefix(src, "."):
+			return nil, nil, fmt.Errorf("invalid --volume %q: host path %q must be absolute", e, src)
+		default:
+			volumes = append(volumes, &specgen.NamedVolume{
+				Name:    src,
+				Dest:    dest,
+				Options: mountOpts,
+			})
+		}
+	}
+	return mounts, volumes, nil
+}
+
+// parseTmpfs converts PATH[:OPTS] entries into tmpfs mounts.  OPTS is a
+// comma-separated list of mount options.  The defaults are
+// size=64m,mode=1777,rw,nosuid,nodev; size and mode are checked here, and
+// anything else is passed on to the runtime.
+func parseTmpfs(entries []string) ([]spec.Mount, error) {
+	var mounts []spec.Mount
+	for _, e := range entries {
+		fields := strings.SplitN(e, ":", 2)
+		dest := fields[0]
+		if !filepath.IsAbs(dest) {
+			return nil, fmt.Errorf("invalid --tmpfs %q: container path %q must be absolute", e, dest)
+		}
+
+		size, mode := "size=64m", "mode=1777"
+		var extra []string
+		if len(fields) == 2 {
+			for _, o := range strings.Split(fields[1], ",") {
+				switch {
+				case o == "":
+					return nil, fmt.Errorf("invalid --tmpfs %q: empty option", e)
+				case strings.HasPrefix(o, "size="):
+					if _, err := parseSize(strings.TrimPrefix(o, "size=")); err != nil {
+						return nil, fmt.Errorf("invalid --tmpfs %q: %w", e, err)
+					}
+					size = o
+				case strings.HasPrefix(o, "mode="):
+					if _, err := strconv.ParseUint(strings.TrimPrefix(o, "mode="), 8, 32); err != nil {
+						return nil, fmt.Errorf("invalid --tmpfs %q: mode must be octal, such as 1777", e)
+					}
+					mode = o
+				default:
+					extra = append(extra, o)
+				}
+			}
+		}
+
+		mountOpts := []string{size, mode}
+		if !hasOption(extra, "ro") && !hasOption(extra, "rw") {
+			mountOpts = append(mountOpts, "rw")
+		}
+		if !hasOption(extra, "suid") && !hasOption(extra, "nosuid") {
+			mountOpts = append(mountOpts, "nosuid")
+		}
+		if !hasOption(extra, "dev") && !hasOption(extra, "nodev") {
+			mountOpts = append(mountOpts, "nodev")
+		}
+		mounts = append(mounts, spec.Mount{
+			Type:        "tmpfs",
+			Source:      "tmpfs",
+			Destination: dest,
+			Options:     append(mountOpts, extra...),
+		})
+	}
+	return mounts, nil
+}
+
+// hasOption reports whether opt is present in opts.
+func hasOption(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePortMappings converts HOSTPORT:CTRPORT[/PROTO] entries into port
+// mappings.  The protocol defaults to tcp.
+func parsePortMappings(entries []string) ([]specgen.PortMapping, error) {
+	var ports []specgen.PortMapping
+	for _, e := range entries {
+		mapping, proto := e, "tcp"
+		if i := strings.LastIndex(e, "/"); i >= 0 {
+			mapping, proto = e[:i], e[i+1:]
+		}
+		switch proto {
+		case "tcp", "udp", "sctp":
+		default:
+			return nil, fmt.Errorf("invalid --publish %q: unknown protocol %q", e, proto)
+		}
+
+		fields := strings.Split(mapping, ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid --publish %q: expected HOSTPORT:CTRPORT[/PROTO]", e)
+		}
+		hostPort, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --publish %q: bad host port: %w", e, err)
+		}
+		ctrPort, err := strconv.ParseUint(fields[1], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --publish %q: bad container port: %w", e, err)
+		}
+		ports = append(ports, specgen.PortMapping{
+			HostPort:      uint16(hostPort),
+			ContainerPort: uint16(ctrPort),
+			Protocol:      proto,
+		})
+	}
+	return ports, nil
+}
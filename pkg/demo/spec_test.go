@@ -0,0 +1,492 @@
+package demo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/containers/libpod/v2/pkg/specgen"
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", entries: nil, want: map[string]string{}},
+		{name: "pairs", entries: []string{"A=1", "B=two"}, want: map[string]string{"A": "1", "B": "two"}},
+		{name: "empty value", entries: []string{"A="}, want: map[string]string{"A": ""}},
+		{name: "value with equals", entries: []string{"A=b=c"}, want: map[string]string{"A": "b=c"}},
+		{name: "missing equals", entries: []string{"A"}, wantErr: true},
+		{name: "empty key", entries: []string{"=1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnv(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEnv(%q) error = %v, wantErr %v", tt.entries, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEnv(%q) = %v, want %v", tt.entries, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePortMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []specgen.PortMapping
+		wantErr bool
+	}{
+		{name: "empty", entries: nil, want: nil},
+		{name: "default tcp", entries: []string{"8080:80"},
+			want: []specgen.PortMapping{{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}}},
+		{name: "udp", entries: []string{"5353:53/udp"},
+			want: []specgen.PortMapping{{HostPort: 5353, ContainerPort: 53, Protocol: "udp"}}},
+		{name: "unknown protocol", entries: []string{"80:80/icmp"}, wantErr: true},
+		{name: "single port", entries: []string{"80"}, wantErr: true},
+		{name: "not a number", entries: []string{"http:80"}, wantErr: true},
+		{name: "out of range", entries: []string{"70000:80"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePortMappings(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePortMappings(%q) error = %v, wantErr %v", tt.entries, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePortMappings(%q) = %+v, want %+v", tt.entries, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageListFilters(t *testing.T) {
+	given := map[string][]string{"reference": {"fedora*"}, "label": {"app=demo"}}
+	got, err := imageListFilters(given, true, []string{"tier", "team=web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"reference": {"fedora*"},
+		"label":     {"app=demo", "tier", "team=web"},
+		"dangling":  {"true"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imageListFilters = %v, want %v", got, want)
+	}
+	if len(given["label"]) != 1 {
+		t.Errorf("imageListFilters changed the --image-filter filters to %v", given)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		filters  map[string][]string
+		dangling bool
+		labels   []string
+	}{
+		{"contradicts dangling=false", map[string][]string{"dangling": {"false"}}, true, nil},
+		{"empty label", nil, false, []string{""}},
+		{"label without key", nil, false, []string{"=web"}},
+	} {
+		if _, err := imageListFilters(tt.filters, tt.dangling, tt.labels); err == nil {
+			t.Errorf("%s: imageListFilters succeeded, expected an error", tt.name)
+		}
+	}
+}
+
+func TestParseVolumes(t *testing.T) {
+	tests := []struct {
+		name        string
+		entries     []string
+		wantMounts  []spec.Mount
+		wantVolumes []*specgen.NamedVolume
+		wantErr     bool
+	}{
+		{name: "empty", entries: nil},
+		{name: "bind mount", entries: []string{"/srv:/data"},
+			wantMounts: []spec.Mount{{Type: "bind", Source: "/srv", Destination: "/data", Options: []string{"rbind"}}}},
+		{name: "bind mount with options", entries: []string{"/srv:/data:ro,z"},
+			wantMounts: []spec.Mount{{Type: "bind", Source: "/srv", Destination: "/data", Options: []string{"ro", "z", "rbind"}}}},
+		{name: "named volume", entries: []string{"cache:/cache"},
+			wantVolumes: []*specgen.NamedVolume{{Name: "cache", Dest: "/cache"}}},
+		{name: "relative host path", entries: []string{"./srv:/data"}, wantErr: true},
+		{name: "relative container path", entries: []string{"/srv:data"}, wantErr: true},
+		{name: "missing destination", entries: []string{"/srv"}, wantErr: true},
+		{name: "empty options", entries: []string{"/srv:/data:"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mounts, volumes, err := parseVolumes(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVolumes(%q) error = %v, wantErr %v", tt.entries, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(mounts, tt.wantMounts) {
+				t.Errorf("parseVolumes(%q) mounts = %+v, want %+v", tt.entries, mounts, tt.wantMounts)
+			}
+			if !reflect.DeepEqual(volumes, tt.wantVolumes) {
+				t.Errorf("parseVolumes(%q) volumes = %+v, want %+v", tt.entries, volumes, tt.wantVolumes)
+			}
+		})
+	}
+}
+
+func TestParseMountTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		entries     []string
+		wantMounts  []spec.Mount
+		wantVolumes []*specgen.NamedVolume
+		wantImages  []imageVolume
+		wantErr     bool
+	}{
+		{name: "empty", entries: nil},
+		{name: "bind", entries: []string{"type=bind,source=/srv,target=/data"},
+			wantMounts: []spec.Mount{{Type: "bind", Source: "/srv", Destination: "/data", Options: []string{"rbind"}}}},
+		{name: "bind with options", entries: []string{"type=bind,src=/srv,dst=/data,readonly,bind-propagation=rslave,relabel=private,bind-nonrecursive=true"},
+			wantMounts: []spec.Mount{{Type: "bind", Source: "/srv", Destination: "/data", Options: []string{"ro", "rslave", "Z", "bind"}}}},
+		{name: "bind readonly false", entries: []string{"type=bind,source=/srv,destination=/data,ro=false"},
+			wantMounts: []spec.Mount{{Type: "bind", Source: "/srv", Destination: "/data", Options: []string{"rbind"}}}},
+		{name: "bind relative source", entries: []string{"type=bind,source=srv,target=/data"}, wantErr: true},
+		{name: "bind bad propagation", entries: []string{"type=bind,source=/srv,target=/data,bind-propagation=sideways"}, wantErr: true},
+		{name: "bind tmpfs key", entries: []string{"type=bind,source=/srv,target=/data,tmpfs-size=1m"}, wantErr: true},
+		{name: "volume", entries: []string{"type=volume,source=cache,target=/cache,readonly,volume-nocopy"},
+			wantVolumes: []*specgen.NamedVolume{{Name: "cache", Dest: "/cache", Options: []string{"ro", "nocopy"}}}},
+		{name: "anonymous volume", entries: []string{"type=volume,target=/cache"},
+			wantVolumes: []*specgen.NamedVolume{{Dest: "/cache"}}},
+		{name: "volume with a path", entries: []string{"type=volume,source=/srv,target=/cache"}, wantErr: true},
+		{name: "volume propagation", entries: []string{"type=volume,source=cache,target=/cache,bind-propagation=shared"}, wantErr: true},
+		{name: "tmpfs", entries: []string{"type=tmpfs,target=/scratch"},
+			wantMounts: []spec.Mount{{Type: "tmpfs", Source: "tmpfs", Destination: "/scratch", Options: []string{"size=64m", "mode=1777", "rw", "nosuid", "nodev"}}}},
+		{name: "tmpfs with options", entries: []string{"type=tmpfs,target=/scratch,tmpfs-size=16m,tmpfs-mode=700,readonly"},
+			wantMounts: []spec.Mount{{Type: "tmpfs", Source: "tmpfs", Destination: "/scratch", Options: []string{"size=16m", "mode=700", "nosuid", "nodev", "ro"}}}},
+		{name: "tmpfs with a source", entries: []string{"type=tmpfs,source=/srv,target=/scratch"}, wantErr: true},
+		{name: "tmpfs bad size", entries: []string{"type=tmpfs,target=/scratch,tmpfs-size=lots"}, wantErr: true},
+		{name: "tmpfs bad mode", entries: []string{"type=tmpfs,target=/scratch,tmpfs-mode=999"}, wantErr: true},
+		{name: "image", entries: []string{"type=image,source=fedora,target=/fedora"},
+			wantImages: []imageVolume{{Source: "fedora", Destination: "/fedora"}}},
+		{name: "image read-write", entries: []string{"type=image,src=fedora,target=/fedora,rw"},
+			wantImages: []imageVolume{{Source: "fedora", Destination: "/fedora", ReadWrite: true}}},
+		{name: "image without source", entries: []string{"type=image,target=/fedora"}, wantErr: true},
+		{name: "image readonly", entries: []string{"type=image,source=fedora,target=/fedora,readonly"}, wantErr: true},
+		{name: "missing type", entries: []string{"source=/srv,target=/data"}, wantErr: true},
+		{name: "unknown type", entries: []string{"type=nfs,target=/data"}, wantErr: true},
+		{name: "missing target", entries: []string{"type=tmpfs"}, wantErr: true},
+		{name: "relative target", entries: []string{"type=tmpfs,target=scratch"}, wantErr: true},
+		{name: "unknown key", entries: []string{"type=tmpfs,target=/scratch,colour=blue"}, wantErr: true},
+		{name: "key twice", entries: []string{"type=bind,source=/a,src=/b,target=/data"}, wantErr: true},
+		{name: "value missing", entries: []string{"type=bind,source,target=/data"}, wantErr: true},
+		{name: "bad bool", entries: []string{"type=tmpfs,target=/scratch,readonly=maybe"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mounts, volumes, images, err := parseMountTypes(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMountTypes(%q) error = %v, wantErr %v", tt.entries, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(mounts, tt.wantMounts) {
+				t.Errorf("parseMountTypes(%q) mounts = %+v, want %+v", tt.entries, mounts, tt.wantMounts)
+			}
+			if !reflect.DeepEqual(volumes, tt.wantVolumes) {
+				t.Errorf("parseMountTypes(%q) volumes = %+v, want %+v", tt.entries, volumes, tt.wantVolumes)
+			}
+			if !reflect.DeepEqual(images, tt.wantImages) {
+				t.Errorf("parseMountTypes(%q) images = %+v, want %+v", tt.entries, images, tt.wantImages)
+			}
+		})
+	}
+}
+
+func TestBuildSpec(t *testing.T) {
+	opts := Options{
+		Image:          "fedora",
+		Name:           "demo",
+		Command:        []string{"sleep", "10"},
+		Env:            []string{"A=1"},
+		Labels:         []string{"app=demo"},
+		Annotations:    []string{"org.example.tier=web"},
+		Volumes:        []string{"/srv:/data"},
+		Publish:        []string{"8080:80"},
+		Restart:        "on-failure",
+		RestartRetries: 3,
+		User:           "1000:1000",
+		Workdir:        "/work",
+		Memory:         "128m",
+		CPUs:           0.5,
+		OOMScoreAdj:    -500,
+	}
+	s, err := buildSpec(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []struct {
+		field     string
+		got, want interface{}
+	}{
+		{"Image", s.Image, "fedora"},
+		{"Name", s.Name, "demo"},
+		{"Command", s.Command, []string{"sleep", "10"}},
+		{"Env", s.Env, map[string]string{"A": "1"}},
+		{"Labels", s.Labels, map[string]string{"app": "demo"}},
+		{"Annotations", s.Annotations, map[string]string{"org.example.tier": "web"}},
+		{"Mounts", len(s.Mounts), 1},
+		{"PortMappings", s.PortMappings, []specgen.PortMapping{{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}}},
+		{"RestartPolicy", s.RestartPolicy, "on-failure"},
+		{"RestartRetries", *s.RestartRetries, uint(3)},
+		{"User", s.User, "1000:1000"},
+		{"WorkDir", s.WorkDir, "/work"},
+		{"Memory", *s.ResourceLimits.Memory.Limit, int64(128 << 20)},
+		{"CPU quota", *s.ResourceLimits.CPU.Quota, int64(50000)},
+		{"CPU period", *s.ResourceLimits.CPU.Period, uint64(100000)},
+		{"OOMScoreAdj", *s.OOMScoreAdj, -500},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.field, c.got, c.want)
+		}
+	}
+}
+
+func TestBuildSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"bad env", Options{Env: []string{"A"}}},
+		{"bad port", Options{Publish: []string{"x:80"}}},
+		{"bad restart policy", Options{Restart: "sometimes"}},
+		{"retries without on-failure", Options{Restart: "always", RestartRetries: 1}},
+		{"relative workdir", Options{Workdir: "work"}},
+		{"bad umask", Options{Umask: "0899", Command: []string{"true"}}},
+		{"umask without a process", Options{Umask: "0077"}},
+		{"bad user", Options{User: "1000:group"}},
+		{"bad group", Options{GroupAdd: []string{"wheel", "dial out"}}},
+		{"missing seccomp profile", Options{SeccompProfile: "/nonexistent/seccomp.json"}},
+		{"bad memory", Options{Memory: "lots"}},
+		{"bad shm size", Options{ShmSize: "64x"}},
+		{"shm size with host IPC", Options{ShmSize: "64m", IpcNS: "host"}},
+		{"bad annotation", Options{Annotations: []string{"=web"}}},
+		{"relative device", Options{Devices: []string{"ttyUSB0"}}},
+		{"bad device permissions", Options{Devices: []string{"/dev/fuse:/dev/fuse:rx"}}},
+		{"bad namespace mode", Options{PidNS: "shared"}},
+		{"relative namespace path", Options{IpcNS: "ns:proc/1/ns/ipc"}},
+		{"bad userns", Options{UserNS: "private"}},
+		{"alias without network", Options{NetworkAliases: []string{"db"}}},
+		{"bad alias", Options{Network: "demo", NetworkAliases: []string{"-db"}}},
+		{"short uidmap", Options{UIDMaps: []string{"0:1000"}}},
+		{"empty gidmap", Options{GIDMaps: []string{"0:1000:0"}}},
+		{"auto-remove with restart", Options{AutoRemove: true, Restart: "always"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Image = "fedora"
+			if _, err := buildSpec(tt.opts); err == nil {
+				t.Error("buildSpec succeeded, expected an error")
+			}
+		})
+	}
+}
+
+func TestBuildSpecAutoRemove(t *testing.T) {
+	for _, autoRemove := range []bool{false, true} {
+		s, err := buildSpec(Options{Image: "fedora", AutoRemove: autoRemove, Restart: "no"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Remove != autoRemove {
+			t.Errorf("AutoRemove %v: Remove = %v", autoRemove, s.Remove)
+		}
+	}
+}
+
+func TestCheckSeccompProfile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content string
+		wantErr       bool
+	}{
+		{"profile", `{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": []}`, false},
+		{"not JSON", `defaultAction: SCMP_ACT_ERRNO`, true},
+		{"not a profile", `{"syscalls": []}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := checkSeccompProfile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkSeccompProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != path {
+				t.Errorf("checkSeccompProfile() = %q, want %q", got, path)
+			}
+		})
+	}
+	if got, err := checkSeccompProfile("unconfined"); err != nil || got != "unconfined" {
+		t.Errorf("checkSeccompProfile(unconfined) = %q, %v", got, err)
+	}
+}
+
+func TestBuildSpecFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.json")
+	content := `{"image": "alpine", "hostname": "web", "env": {"A": "1", "B": "2"}, "oci_runtime": "crun"}`
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := buildSpec(Options{SpecFile: path, Env: []string{"B=3"}, Hostname: "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Image != "alpine" || s.OCIRuntime != "crun" {
+		t.Errorf("image %q, runtime %q; want the file's alpine and crun", s.Image, s.OCIRuntime)
+	}
+	if s.Hostname != "db" {
+		t.Errorf("hostname %q, want the flag's db", s.Hostname)
+	}
+	if want := map[string]string{"A": "1", "B": "3"}; !reflect.DeepEqual(s.Env, want) {
+		t.Errorf("env %v, want %v", s.Env, want)
+	}
+}
+
+func TestLoadSpecErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content, want string
+	}{
+		{"syntax", "{\n  \"image\": \"alpine\",\n}", "line 3, column 1"},
+		{"type", `{"env": ["A=1"]}`, "field env"},
+		{"unknown", `{"imag": "alpine"}`, `"imag"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadSpec(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadSpec() error = %v, want one mentioning %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRootfs(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := checkRootfs(dir); err == nil {
+		t.Error("checkRootfs() of an empty directory succeeded, expected an error")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "hello"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := checkRootfs(dir); err != nil || got != dir {
+		t.Errorf("checkRootfs() = %q, %v; want %q", got, err, dir)
+	}
+	if _, err := checkRootfs(filepath.Join(dir, "missing")); err == nil {
+		t.Error("checkRootfs() of a missing directory succeeded, expected an error")
+	}
+}
+
+func TestBuildSpecEntrypoint(t *testing.T) {
+	s, err := buildSpec(Options{Image: "fedora"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Entrypoint != nil {
+		t.Errorf("Entrypoint = %#v without --entrypoint, want nil to use the image's", s.Entrypoint)
+	}
+	s, err = buildSpec(Options{Image: "fedora", NoEntrypoint: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Entrypoint == nil || len(s.Entrypoint) != 0 {
+		t.Errorf("Entrypoint = %#v with --no-entrypoint, want empty but not nil", s.Entrypoint)
+	}
+}
+
+func TestBuildSpecEnvHost(t *testing.T) {
+	for k, v := range map[string]string{"DEMO_INHERITED": "host", "DEMO_OVERRIDDEN": "host"} {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Unsetenv(k)
+	}
+	s, err := buildSpec(Options{Image: "fedora", EnvHost: true, Env: []string{"DEMO_OVERRIDDEN=flag"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Env["DEMO_INHERITED"]; got != "host" {
+		t.Errorf("DEMO_INHERITED = %q, want it inherited from the host", got)
+	}
+	if got := s.Env["DEMO_OVERRIDDEN"]; got != "flag" {
+		t.Errorf("DEMO_OVERRIDDEN = %q, want --env to win over the host", got)
+	}
+	s, err = buildSpec(Options{Image: "fedora"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Env["DEMO_INHERITED"]; ok {
+		t.Error("host environment inherited without --env-host")
+	}
+}
+
+func TestBuildSpecTimezone(t *testing.T) {
+	if _, err := os.Stat(filepath.Join(zoneinfoDir, "UTC")); err != nil {
+		t.Skip("no timezone database on this system")
+	}
+	s, err := buildSpec(Options{Image: "fedora", TZ: "UTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["TZ"] != "UTC" {
+		t.Errorf("TZ = %q, want UTC", s.Env["TZ"])
+	}
+	if len(s.Mounts) != 1 || s.Mounts[0].Destination != "/etc/localtime" || s.Mounts[0].Source != filepath.Join(zoneinfoDir, "UTC") {
+		t.Errorf("Mounts = %+v, want the UTC zone file bound at /etc/localtime", s.Mounts)
+	}
+	s, err = buildSpec(Options{Image: "fedora", TZ: "UTC", Env: []string{"TZ=:/etc/localtime"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Env["TZ"] != ":/etc/localtime" {
+		t.Errorf("TZ = %q, want --env to win", s.Env["TZ"])
+	}
+	for _, name := range []string{"Nowhere/Special", "../../etc/passwd", "/etc/localtime", "Europe"} {
+		if _, err := checkTimezone(name); err == nil {
+			t.Errorf("checkTimezone(%q) succeeded, expected an error", name)
+		}
+	}
+}
+
+func TestBuildSpecShellParse(t *testing.T) {
+	s, err := buildSpec(Options{Image: "fedora", ShellParse: true, Entrypoint: "/usr/bin/env -i", Command: []string{`sh -c 'echo hi'`, "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/usr/bin/env", "-i"}; !reflect.DeepEqual(s.Entrypoint, want) {
+		t.Errorf("Entrypoint = %q, want %q", s.Entrypoint, want)
+	}
+	if want := []string{"sh", "-c", "echo hi", "x"}; !reflect.DeepEqual(s.Command, want) {
+		t.Errorf("Command = %q, want %q", s.Command, want)
+	}
+	if _, err := buildSpec(Options{Image: "fedora", ShellParse: true, Command: []string{`echo 'hi`}}); err == nil {
+		t.Error("buildSpec with an unterminated quote succeeded, expected an error")
+	}
+}
@@ -0,0 +1,91 @@
+package demo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/containers/libpod/v2/pkg/specgen"
+)
+
+// loadSpec reads a SpecGenerator from a JSON file, using the same field
+// names as the service's create endpoint, e.g. {"image": "fedora",
+// "env": {"A": "1"}}.  Fields the SpecGenerator does not have are
+// rejected, so that a misspelt one is not silently ignored.
+func loadSpec(path string) (*specgen.SpecGenerator, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading --spec: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	s := &specgen.SpecGenerator{}
+	if err := dec.Decode(s); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			// The offset is just past the offending byte
+			line, col := position(data, syntaxErr.Offset-1)
+			return nil, fmt.Errorf("parsing --spec %s at line %d, column %d: %w", path, line, col, err)
+		case errors.As(err, &typeErr):
+			return nil, fmt.Errorf("parsing --spec %s: field %s cannot be a JSON %s, it must be a %s", path, typeErr.Field, typeErr.Value, typeErr.Type)
+		}
+		return nil, fmt.Errorf("parsing --spec %s: %w", path, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("parsing --spec %s: more than one JSON value", path)
+	}
+	return s, nil
+}
+
+// position converts the offset of a byte in data into its line and
+// column, both counting from 1.
+func position(data []byte, offset int64) (line, col int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// mergeSpec applies the fields that the flags set in flags on top of
+// base, which it returns.  A field counts as set when it is not its zero
+// value; maps such as env and labels are merged key by key, every other
+// field is replaced as a whole.  This means a flag can turn a boolean on
+// but not off, and flags left at their defaults leave the file alone.
+func mergeSpec(base, flags *specgen.SpecGenerator) *specgen.SpecGenerator {
+	mergeStruct(reflect.ValueOf(base).Elem(), reflect.ValueOf(flags).Elem())
+	return base
+}
+
+// mergeStruct copies the non-zero fields of src into dst, descending
+// into the embedded config structs that make up a SpecGenerator.
+func mergeStruct(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Type().Field(i)
+		d, v := dst.Field(i), src.Field(i)
+		switch {
+		case !d.CanSet() || v.IsZero():
+		case field.Anonymous && v.Kind() == reflect.Struct:
+			mergeStruct(d, v)
+		case v.Kind() == reflect.Map:
+			if d.IsNil() {
+				d.Set(reflect.MakeMap(v.Type()))
+			}
+			for _, k := range v.MapKeys() {
+				d.SetMapIndex(k, v.MapIndex(k))
+			}
+		default:
+			d.Set(v)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+)
+
+// watchState polls the inspect data of container id in the background and
+// prints its status each time it changes, e.g. created, running, stopping
+// and exited, until the container exits or is removed, or the returned
+// function is called.  Polling can miss a state that lasts less than the
+// interval, but the same state is never printed twice in a row.
+func watchState(conn context.Context, id string, interval time.Duration, out io.Writer) (stop func()) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := ""
+		for {
+			data, err := containers.Inspect(conn, id, nil)
+			switch {
+			case err != nil && isNotFound(err):
+				return
+			case err != nil:
+				fmt.Fprintln(os.Stderr, "Watching state:", err)
+				return
+			case data.State.Status != last:
+				last = data.State.Status
+				fmt.Fprintf(out, "State %s: %s\n", time.Now().Format("15:04:05.000"), last)
+			}
+			if last == "exited" {
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		<-done
+	}
+}
@@ -0,0 +1,134 @@
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/containers/libpod/v2/pkg/bindings"
+)
+
+// statsSample holds the parts of a stats sample that we print.  The field
+// names follow the JSON returned by the stats endpoint.
+type statsSample struct {
+	CPUStats struct {
+		CPU float64 `json:"cpu"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage uint64 `json:"usage"`
+		Limit uint64 `json:"limit"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+}
+
+// netIO sums the received and transmitted bytes over all networks.
+func (s statsSample) netIO() (rx, tx uint64) {
+	for _, n := range s.Networks {
+		rx += n.RxBytes
+		tx += n.TxBytes
+	}
+	return rx, tx
+}
+
+// collectStats prints resource usage samples of a running container for
+// the given duration, followed by their average.  If the connection drops,
+// the stream is opened again on a new one and the samples carry on.
+func collectStats(ctx context.Context, streams *redialer, id string, duration time.Duration, out io.Writer) error {
+	// The endpoint streams one JSON document per second.  Decode them in
+	// the background; cancelling statsCtx on return stops the reader.
+	statsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	samples := make(chan statsSample)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- streams.follow(statsCtx, "stats", func(conn context.Context) error {
+			return readStats(statsCtx, conn, id, samples)
+		})
+	}()
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	var (
+		n           int
+		cpu         float64
+		mem, rx, tx uint64
+	)
+	for {
+		select {
+		case s := <-samples:
+			sRx, sTx := s.netIO()
+			fmt.Fprintf(out, "CPU %6.2f%%  MEM %s / %s  NET %s / %s\n",
+				s.CPUStats.CPU, humanSize(int64(s.MemoryStats.Usage)), humanSize(int64(s.MemoryStats.Limit)), humanSize(int64(sRx)), humanSize(int64(sTx)))
+			n++
+			cpu += s.CPUStats.CPU
+			mem += s.MemoryStats.Usage
+			rx, tx = sRx, sTx
+		case err := <-errc:
+			if err != nil {
+				return err
+			}
+			return printStatsAverage(out, n, cpu, mem, rx, tx)
+		case <-timer.C:
+			return printStatsAverage(out, n, cpu, mem, rx, tx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// readStats opens the stats stream of container id on conn and sends each
+// sample to samples until the stream ends or ctx is done.
+//
+// containers.Stats is not implemented in this version of the bindings, so
+// we call the stats endpoint directly through the bindings' connection.
+func readStats(ctx, conn context.Context, id string, samples chan<- statsSample) error {
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("stream", "true")
+	response, err := client.DoRequest(nil, http.MethodGet, "/containers/%s/stats", params, nil, id)
+	if err != nil {
+		return fmt.Errorf("getting stats for container %s: %w", id, err)
+	}
+	if !response.IsSuccess() {
+		return fmt.Errorf("getting stats for container %s: %w", id, response.Process(nil))
+	}
+	defer response.Body.Close()
+
+	dec := json.NewDecoder(response.Body)
+	for {
+		var s statsSample
+		if err := dec.Decode(&s); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading stats for container %s: %w", id, err)
+		}
+		select {
+		case samples <- s:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// printStatsAverage prints the summary line of collectStats.  Network
+// counters are cumulative, so the last sample is reported as-is.
+func printStatsAverage(out io.Writer, n int, cpu float64, mem, rx, tx uint64) error {
+	if n == 0 {
+		fmt.Fprintln(out, "No stats samples were received")
+		return nil
+	}
+	fmt.Fprintf(out, "Average over %d samples: CPU %.2f%%  MEM %s  NET %s / %s\n",
+		n, cpu/float64(n), humanSize(int64(mem/uint64(n))), humanSize(int64(rx)), humanSize(int64(tx)))
+	return nil
+}
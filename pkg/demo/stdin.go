@@ -0,0 +1,159 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/containers/libpod/v2/pkg/bindings"
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+)
+
+// stdinPiped reports whether our stdin is a pipe or a file, whose data
+// can be passed on to the container, rather than a terminal or a device
+// such as /dev/null.
+func stdinPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// execInput returns the stdin for --exec: ours when it is piped, and nil,
+// for none, otherwise.  A terminal is left alone, as the exec session has
+// none to pass what is typed to.
+func execInput() io.Reader {
+	if stdinPiped() {
+		return os.Stdin
+	}
+	return nil
+}
+
+// inputConn is a connection to send input over, whose sending side can
+// be closed on its own, as that of unix, TCP, TLS and SSH connections can.
+type inputConn interface {
+	io.Writer
+	CloseWrite() error
+}
+
+// hijack makes a request whose connection the service takes over for a
+// stream, as attach and exec start do, and returns that connection, to
+// write the input to, and the response body, to read the output from.
+//
+// The bindings' own attach calls copy stdin to the container but never
+// tell it where the input ends, so a command reading it until end-of-file
+// waits forever.  hijack gives us the connection so that we can.  The
+// request gets a transport of its own, leaving the one the other calls
+// share as it was, which the bindings' attach calls do not.
+func hijack(conn context.Context, body io.Reader, endpoint string, params url.Values, headers map[string]string, pathValues ...string) (inputConn, io.ReadCloser, error) {
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	transport, ok := client.Client.Transport.(*http.Transport)
+	if !ok || transport.DialContext == nil {
+		return nil, nil, errors.New("this connection to the service cannot carry the input")
+	}
+	var socket net.Conn
+	own := *client
+	own.Client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			c, err := transport.DialContext(ctx, network, address)
+			if err == nil && socket == nil {
+				socket = c
+			}
+			return c, err
+		},
+	}}
+	response, err := own.DoRequest(body, http.MethodPost, endpoint, params, headers, pathValues...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !(response.IsSuccess() || response.IsInformational()) {
+		return nil, nil, response.Process(nil)
+	}
+	w, ok := socket.(inputConn)
+	if !ok {
+		response.Body.Close()
+		return nil, nil, errors.New("this connection to the service cannot signal the end of the input")
+	}
+	return w, response.Body, nil
+}
+
+// streamIO copies stdin to the container over w, then closes w's sending
+// side, so the container reads end-of-file where the input ends, just as
+// from a pipe.  Meanwhile it copies the output to stdout and stderr until
+// the service ends it, demultiplexing it unless the container has a
+// terminal.  A command may exit without reading all of its input, so the
+// copy of the input is not waited for.
+func streamIO(ctx context.Context, w inputConn, output io.ReadCloser, tty bool, stdin io.Reader, stdout, stderr io.Writer) error {
+	defer output.Close()
+	go func() {
+		if _, err := io.Copy(w, stdin); err == nil {
+			w.CloseWrite()
+		}
+	}()
+	return withContext(ctx, func() error {
+		if tty {
+			_, err := io.Copy(stdout, output)
+			return err
+		}
+		return demuxOutput(output, stdout, stderr)
+	})
+}
+
+// demuxOutput copies the output of a container without a terminal, which
+// the service sends in frames tagged with the stream they came from, to
+// stdout and stderr until it ends.
+func demuxOutput(r io.Reader, stdout, stderr io.Writer) error {
+	buffer := make([]byte, 1024)
+	for {
+		fd, l, err := containers.DemuxHeader(r, buffer)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		frame, err := containers.DemuxFrame(r, buffer, l)
+		if err != nil {
+			return err
+		}
+		switch fd {
+		case 1:
+			_, err = stdout.Write(frame)
+		case 2:
+			_, err = stderr.Write(frame)
+		case 3:
+			return fmt.Errorf("error from the service: %s", frame)
+		}
+		// Stream 0 is our own input, echoed back
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// attachPiped is attachContainer for a piped stdin: once the input ends,
+// the container's stdin is closed, rather than left open for more.
+func attachPiped(ctx context.Context, conn context.Context, id, detachKeys string) error {
+	data, err := containers.Inspect(conn, id, nil)
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("detachKeys", detachKeys)
+	params.Set("stream", "true")
+	params.Set("stdin", "true")
+	params.Set("stdout", "true")
+	params.Set("stderr", "true")
+	headers := map[string]string{"Connection": "Upgrade", "Upgrade": "tcp"}
+	w, output, err := hijack(conn, nil, "/containers/%s/attach", params, headers, id)
+	if err != nil {
+		return err
+	}
+	return streamIO(ctx, w, output, data.Config.Tty, os.Stdin, os.Stdout, os.Stderr)
+}
@@ -0,0 +1,42 @@
+package demo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// frame encodes data as one frame of stream fd, as the service sends the
+// output of a container without a terminal.
+func frame(fd byte, data string) []byte {
+	header := make([]byte, 8)
+	header[0] = fd
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+func TestDemuxOutput(t *testing.T) {
+	var in bytes.Buffer
+	in.Write(frame(1, "hello "))
+	in.Write(frame(0, "echoed input"))
+	in.Write(frame(2, "oops\n"))
+	in.Write(frame(1, strings.Repeat("x", 2000)))
+
+	var stdout, stderr bytes.Buffer
+	if err := demuxOutput(&in, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if want := "hello " + strings.Repeat("x", 2000); stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if stderr.String() != "oops\n" {
+		t.Errorf("stderr = %q, want %q", stderr.String(), "oops\n")
+	}
+
+	in.Reset()
+	in.Write(frame(3, "exec failed"))
+	if err := demuxOutput(&in, &stdout, &stderr); err == nil || !strings.Contains(err.Error(), "exec failed") {
+		t.Errorf("demuxOutput = %v, want the service's error", err)
+	}
+}
@@ -0,0 +1,87 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/containers/libpod/v2/libpod/define"
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	"github.com/containers/libpod/v2/pkg/specgen"
+	"github.com/lsm5/bindings-sample/internal/retry"
+)
+
+// exitedError is a container exiting non-zero, which supervise retries.
+type exitedError int32
+
+func (e exitedError) Error() string {
+	return fmt.Sprintf("container exited with code %d", int32(e))
+}
+
+// supervise waits for the container *id to exit and, each time it exits
+// non-zero, removes it and creates and starts a new one from s and
+// extras, up to maxRestarts times with a doubling delay.  *id is updated
+// to the newest container, which restarted is told about once it has
+// started.  It returns the exit code of the last container.
+//
+// Unlike a restart policy, which the service applies to the same
+// container, this is done from the client, so each run starts from a
+// fresh container.
+func supervise(ctx, conn context.Context, id *string, s *specgen.SpecGenerator, extras specExtras, maxRestarts int, backoff time.Duration, logger *slog.Logger, out io.Writer, restarted func(id string)) (int32, error) {
+	var exitCode int32
+	policy := retry.Policy{
+		Attempts:   maxRestarts + 1,
+		Delay:      backoff,
+		Multiplier: 2,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			logger.Info(fmt.Sprintf("Container exited with code %d; recreating it in %s (restart %d of %d)...", exitCode, delay, attempt, maxRestarts))
+		},
+	}
+	attempt := 0
+	err := retry.Do(ctx, policy, func() error {
+		attempt++
+		if attempt > 1 {
+			if err := removeContainer(conn, *id); err != nil {
+				return retry.Permanent(err)
+			}
+			r, err := createContainer(conn, s, extras, false, out)
+			if err != nil {
+				return retry.Permanent(err)
+			}
+			*id = r.ID
+			logger.Debug("containers.Start", "id", r.ID)
+			if err := containers.Start(conn, r.ID, nil); err != nil {
+				return retry.Permanent(fmt.Errorf("starting container %s: %w", r.ID, err))
+			}
+			restarted(r.ID)
+		}
+
+		condition := define.ContainerStateExited
+		logger.Debug("containers.Wait", "id", *id, "condition", condition)
+		err := withContext(ctx, func() error {
+			var err error
+			exitCode, err = containers.Wait(conn, *id, &condition)
+			return err
+		})
+		switch {
+		case err != nil:
+			return retry.Permanent(fmt.Errorf("waiting for container %s to exit: %w", *id, err))
+		case exitCode != 0:
+			return exitedError(exitCode)
+		}
+		return nil
+	})
+
+	var exited exitedError
+	switch {
+	case ctx.Err() != nil:
+		return 0, ctx.Err()
+	case errors.As(err, &exited):
+		logger.Info(fmt.Sprintf("Container exited with code %d; giving up after %d restarts", exitCode, maxRestarts))
+		return exitCode, nil
+	}
+	return exitCode, err
+}
@@ -0,0 +1,56 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+
+	"github.com/containers/libpod/v2/pkg/bindings"
+)
+
+// generateSystemd writes systemd units for the container into dir and
+// returns the paths written.  The service returns one unit per container,
+// plus one for the pod when the container is in one.
+//
+// There is no generate systemd binding in this version, and the endpoint
+// only exists in newer services, so we call it directly through the
+// bindings' connection.
+func generateSystemd(conn context.Context, id, dir string) ([]string, error) {
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("useName", "true")
+	response, err := client.DoRequest(nil, http.MethodGet, "/generate/%s/systemd", params, nil, id)
+	if err != nil {
+		return nil, fmt.Errorf("generating systemd units: %w", err)
+	}
+	units := make(map[string]string)
+	if err := response.Process(&units); err != nil {
+		// An unknown route is a plain-text 404 rather than an API error
+		if response.StatusCode == http.StatusNotFound && !isNotFound(err) {
+			return nil, fmt.Errorf("generating systemd units: the service does not support it; Podman 2.1 or later is required")
+		}
+		return nil, fmt.Errorf("generating systemd units: %w", err)
+	}
+
+	names := make([]string, 0, len(units))
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var paths []string
+	for _, name := range names {
+		path := filepath.Join(dir, name+".service")
+		if err := ioutil.WriteFile(path, []byte(units[name]), 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,70 @@
+package demo
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// stepTiming is how long one of the major steps of a run took.
+type stepTiming struct {
+	Step    string  `json:"step"`
+	Seconds float64 `json:"seconds"`
+}
+
+// timingReport is the --timings report, as printed by --output json.
+type timingReport struct {
+	Steps        []stepTiming `json:"steps"`
+	TotalSeconds float64      `json:"total_seconds"`
+}
+
+// timings records the wall-clock duration of the steps of a run for
+// --timings, in the order they ran.  A nil timings records nothing, so
+// Run can time its steps whether or not the flag was given.
+type timings struct {
+	began time.Time
+	steps []stepTiming
+}
+
+func newTimings() *timings {
+	return &timings{began: time.Now()}
+}
+
+// start begins timing step; calling the returned function ends it.  A
+// step that fails part way is never ended, so it is not reported.
+func (t *timings) start(step string) (end func()) {
+	if t == nil {
+		return func() {}
+	}
+	begin := time.Now()
+	return func() {
+		t.steps = append(t.steps, stepTiming{Step: step, Seconds: time.Since(begin).Seconds()})
+	}
+}
+
+// report returns the steps timed so far and the time since the run began.
+func (t *timings) report() *timingReport {
+	if t == nil {
+		return nil
+	}
+	return &timingReport{Steps: t.steps, TotalSeconds: time.Since(t.began).Seconds()}
+}
+
+// printTimings prints the report as a table, in the order the steps ran.
+// The total includes the steps that were not timed.
+func printTimings(out io.Writer, report *timingReport) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tDURATION")
+	for _, s := range report.Steps {
+		fmt.Fprintf(w, "%s\t%s\n", s.Step, seconds(s.Seconds))
+	}
+	fmt.Fprintf(w, "total\t%s\n", seconds(report.TotalSeconds))
+	w.Flush()
+}
+
+// seconds formats a number of seconds as a duration rounded to the
+// millisecond.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
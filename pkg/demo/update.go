@@ -0,0 +1,84 @@
+package demo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/containers/libpod/v2/pkg/bindings"
+	"github.com/containers/libpod/v2/pkg/bindings/containers"
+	spec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// errUpdateUnsupported is returned by updateResources when the service
+// has no update endpoint.
+var errUpdateUnsupported = errors.New("the service does not support updating containers; Podman 4.3 or later is required")
+
+// updateResources changes the memory limit (bytes) and CPU limit of the
+// running container, leaving a zero value alone, and checks the new limits
+// via inspect.
+//
+// containers.Update does not exist in this version of the bindings, so we
+// call the update endpoint directly through the bindings' connection.  It
+// takes the new limits as an OCI LinuxResources document.
+func updateResources(conn context.Context, id string, memory int64, cpus float64, out io.Writer) error {
+	var resources spec.LinuxResources
+	if memory > 0 {
+		resources.Memory = &spec.LinuxMemory{Limit: &memory}
+	}
+	if cpus > 0 {
+		period := uint64(cpuPeriod)
+		quota := int64(cpus * cpuPeriod)
+		resources.CPU = &spec.LinuxCPU{Period: &period, Quota: &quota}
+	}
+	body, err := json.Marshal(resources)
+	if err != nil {
+		return err
+	}
+
+	client, err := bindings.GetClient(conn)
+	if err != nil {
+		return err
+	}
+	response, err := client.DoRequest(bytes.NewReader(body), http.MethodPost, "/containers/%s/update", nil, nil, id)
+	if err != nil {
+		return fmt.Errorf("updating container %s: %w", id, err)
+	}
+	if err := response.Process(nil); err != nil {
+		switch {
+		// An unknown route is a plain-text 404 rather than an API error
+		case response.StatusCode == http.StatusNotFound && !isNotFound(err):
+			return errUpdateUnsupported
+		case strings.Contains(err.Error(), "cgroup"):
+			return fmt.Errorf("updating container %s: %w (live updates need cgroups v2, with the cpu and memory controllers delegated for rootless containers)", id, err)
+		}
+		return fmt.Errorf("updating container %s: %w", id, err)
+	}
+
+	data, err := containers.Inspect(conn, id, nil)
+	if err != nil {
+		return fmt.Errorf("inspecting container: %w", err)
+	}
+	hc := data.HostConfig
+	if hc == nil {
+		return fmt.Errorf("inspecting container: no host config")
+	}
+	if memory > 0 && hc.Memory != memory {
+		return fmt.Errorf("container memory limit is %d bytes after updating it to %d", hc.Memory, memory)
+	}
+	if cpus > 0 && hc.CpuQuota != int64(cpus*cpuPeriod) {
+		return fmt.Errorf("container CPU quota is %dus after updating it to %dus", hc.CpuQuota, int64(cpus*cpuPeriod))
+	}
+	if memory > 0 {
+		fmt.Fprintf(out, "Container memory limit is now %d bytes\n", hc.Memory)
+	}
+	if cpus > 0 {
+		fmt.Fprintf(out, "Container CPU limit is now %.2f CPUs\n", float64(hc.CpuQuota)/float64(hc.CpuPeriod))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/containers/libpod/v2/pkg/specgen"
+)
+
+// validateOnly finishes a --validate-only run.  It connects to the
+// service, which checks its version too, and checks that the service
+// supports the features opts asks for and that the devices in s exist
+// there, adding any failure to the problems Run found in the options.
+// Then it reports them all in one error, or says all is well.  s is nil
+// if the spec could not be built.
+func validateOnly(ctx context.Context, opts Options, s *specgen.SpecGenerator, problems []error, out io.Writer, logger *slog.Logger) error {
+	// Run reads these only once it knows the service has secrets
+	if _, err := readCreateSecrets(opts.CreateSecrets); err != nil {
+		problems = append(problems, err)
+	}
+	logger.Info("Checking the connection to the service...")
+	conn, err := connectService(ctx, opts, out, logger)
+	if err != nil {
+		problems = append(problems, err)
+	} else {
+		// Strict, so that what would be skipped is reported
+		strict := opts
+		strict.StrictFeatures = true
+		if err := checkFeatures(serviceVersion(conn), &strict, logger); err != nil {
+			problems = append(problems, err)
+		} else if err := checkSecrets(conn, opts); err != nil {
+			// An older service has no secrets to look the names up in
+			problems = append(problems, err)
+		}
+		if s != nil {
+			if err := checkDevices(conn, s.Devices); err != nil {
+				problems = append(problems, err)
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		logger.Info("The options are valid and the service is reachable; nothing was pulled or created")
+		return nil
+	}
+	found := fmt.Sprintf("%d problems", len(problems))
+	if len(problems) == 1 {
+		found = "1 problem"
+	}
+	lines := make([]string, len(problems))
+	for i, p := range problems {
+		lines[i] = "  " + p.Error()
+	}
+	return failed(FailureInvalid, fmt.Errorf("--validate-only found %s:\n%s", found, strings.Join(lines, "\n")))
+}
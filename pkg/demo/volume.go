@@ -0,0 +1,73 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+
+	"github.com/containers/libpod/v2/pkg/bindings/volumes"
+	"github.com/containers/libpod/v2/pkg/domain/entities"
+)
+
+// createVolume creates a named volume and prints where it lives on the
+// host.
+func createVolume(conn context.Context, name string, out io.Writer) error {
+	if _, err := volumes.Create(conn, entities.VolumeCreateOptions{Name: name}); err != nil {
+		return fmt.Errorf("creating volume %s: %w", name, err)
+	}
+	vol, err := volumes.Inspect(conn, name)
+	if err != nil {
+		return fmt.Errorf("inspecting volume %s: %w", name, err)
+	}
+	fmt.Fprintf(out, "Volume %s uses driver %s and is stored at %s\n", vol.Name, vol.Driver, vol.Mountpoint)
+	return nil
+}
+
+// replaceVolume removes the volume called name, and any container using
+// it, if there is one.  This version of the bindings has no volumes.Exists,
+// so an inspect that fails with 404 stands in for it.
+func replaceVolume(conn context.Context, name string, out io.Writer) error {
+	if _, err := volumes.Inspect(conn, name); err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("checking for volume %s: %w", name, err)
+	}
+	fmt.Fprintf(out, "Replacing existing volume %s...\n", name)
+	return removeVolume(conn, name, out)
+}
+
+// removeVolume removes a named volume.  If a container still uses it, the
+// removal is forced, which removes that container first.  A volume that is
+// already gone is not an error.
+func removeVolume(conn context.Context, name string, out io.Writer) error {
+	force := false
+	err := volumes.Remove(conn, name, &force)
+	var apiErr entities.ErrorModel
+	if errors.As(err, &apiErr) && apiErr.Code() == http.StatusConflict {
+		fmt.Fprintf(out, "Volume %s is still in use, removing its containers...\n", name)
+		force = true
+		err = volumes.Remove(conn, name, &force)
+	}
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("removing volume %s: %w", name, err)
+	}
+	return nil
+}
+
+// printVolumeTable prints one line per volume, like `podman volume ls`.
+func printVolumeTable(out io.Writer, list []*entities.VolumeListReport) {
+	if len(list) == 0 {
+		fmt.Fprintln(out, "No volumes found")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "DRIVER\tVOLUME NAME\tMOUNTPOINT")
+	for _, v := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Driver, v.Name, v.Mountpoint)
+	}
+	w.Flush()
+}